@@ -4,6 +4,8 @@ import {
   createIcons,
   Menu,
   ArrowRight,
+  ArrowUpNarrowWide,
+  ArrowDownWideNarrow,
   Globe,
   Moon,
   Bell,
@@ -29,6 +31,27 @@ import {
   PackageMinus,
   NotepadText,
   Clock,
+  Check,
+  Download,
+  History,
+  MessageSquare,
+  MessageSquarePlus,
+  LayoutTemplate,
+  ListChecks,
+  Archive,
+  ArchiveRestore,
+  Save,
+  Flag,
+  Pin,
+  PinOff,
+  GripVertical,
+  Palette,
+  Upload,
+  Hourglass,
+  TriangleAlert,
+  Settings,
+  Rss,
+  ListTodo,
   CircleHelp as Unknown,
   ChevronDown,
   ChevronUp,
@@ -77,6 +100,8 @@ import {
     Unknown,
     Menu,
     ArrowRight,
+    ArrowUpNarrowWide,
+    ArrowDownWideNarrow,
     ChevronDown,
     ChevronUp,
     ChevronLeft,
@@ -106,6 +131,27 @@ import {
     PackageMinus,
     NotepadText,
     Clock,
+    Check,
+    Download,
+    History,
+    MessageSquare,
+    MessageSquarePlus,
+    LayoutTemplate,
+    ListChecks,
+    Archive,
+    ArchiveRestore,
+    Save,
+    Flag,
+    Pin,
+    PinOff,
+    GripVertical,
+    Palette,
+    Upload,
+    Hourglass,
+    TriangleAlert,
+    Settings,
+    Rss,
+    ListTodo,
     
     Bear,
     Bee,
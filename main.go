@@ -15,6 +15,7 @@ import (
 	"slices"
 	"strconv"
 	"time"
+	_ "time/tzdata" // Time zones can be picked on machines without a zone database
 
 	"github.com/angelofallars/htmx-go"
 	"github.com/google/uuid"
@@ -25,13 +26,17 @@ import (
 	gowebly "github.com/gowebly/helpers"
 	bolt "go.etcd.io/bbolt"
 
+	"github.com/pleimann/camel-do/model"
 	"github.com/pleimann/camel-do/services/cal"
 	"github.com/pleimann/camel-do/services/home"
 	"github.com/pleimann/camel-do/services/oauth"
 	"github.com/pleimann/camel-do/services/project"
+	"github.com/pleimann/camel-do/services/settings"
 	"github.com/pleimann/camel-do/services/task"
+	"github.com/pleimann/camel-do/services/tasktemplate"
 	"github.com/pleimann/camel-do/services/timeline"
 	"github.com/pleimann/camel-do/templates/components"
+	"github.com/pleimann/camel-do/utils"
 )
 
 //go:embed all:static
@@ -42,8 +47,10 @@ var credentials string
 
 func main() {
 	var debug, seed bool
+	var workflowFile string
 	flag.BoolVar(&seed, "seed", false, "seed database with some data")
 	flag.BoolVar(&debug, "debug", false, "debug logging mode")
+	flag.StringVar(&workflowFile, "workflow", "", "JSON file with the allowed status transitions")
 	flag.Parse()
 
 	var logLevel slog.Level
@@ -74,7 +81,17 @@ func main() {
 		log.Fatalf("Failed create task sync service! %s", err)
 	}
 
-	calendarService, err = cal.NewCalendarService(&cal.CalendarServiceConfig{}, googleAuth, db)
+	settingsService, err = settings.NewSettingsService(db)
+	if err != nil {
+		log.Fatalf("error creating SettingsService: %s", err)
+	}
+
+	// Days start and end in the user's time zone rather than the server's
+	if saved, err := settingsService.GetSettings(); err == nil {
+		utils.SetLocation(saved.Location())
+	}
+
+	calendarService, err = cal.NewCalendarService(&cal.CalendarServiceConfig{}, googleAuth, db, settingsService)
 	if err != nil {
 		log.Fatalf("error creating CalendarService: %s", err)
 	}
@@ -84,11 +101,26 @@ func main() {
 		log.Fatalf("error creating ProjectService: %s", err)
 	}
 
-	taskService, err = task.NewTaskService(&task.TaskServiceConfig{}, db)
+	workflow, err := readWorkflow(workflowFile)
+	if err != nil {
+		log.Fatalf("error reading workflow: %s", err)
+	}
+
+	taskService, err = task.NewTaskService(&task.TaskServiceConfig{Workflow: workflow}, db)
 	if err != nil {
 		log.Fatalf("error creating TaskService: %s", err)
 	}
 
+	activityService, err = task.NewActivityService(db)
+	if err != nil {
+		log.Fatalf("error creating ActivityService: %s", err)
+	}
+
+	templateService, err = tasktemplate.NewTemplateService(&tasktemplate.TemplateServiceConfig{}, db)
+	if err != nil {
+		log.Fatalf("error creating TemplateService: %s", err)
+	}
+
 	if tasks, err := taskService.GetTodaysTasks(); err == nil && seed {
 		slog.Debug("seeding database", "taskCount", tasks.Len(), "empty", tasks.IsEmpty(), "seedFlag", seed)
 		seedDb(10, taskService, projectService)
@@ -105,10 +137,32 @@ func main() {
 
 const databaseFileName = "camel-do.db"
 
+const calendarSyncInterval = 5 * time.Minute
+
 var taskService *task.TaskService
+var activityService *task.ActivityService
 var taskSyncService *task.TaskSyncService
 var calendarService *cal.CalendarService
+var settingsService *settings.SettingsService
 var projectService *project.ProjectService
+var templateService *tasktemplate.TemplateService
+
+// readWorkflow reads the allowed status transitions from the file, nil leaving
+// the default workflow when no file is given.
+func readWorkflow(fileName string) (model.Workflow, error) {
+	if fileName == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return model.ReadWorkflow(file)
+}
 
 func createDatabase() (*bolt.DB, error) {
 	var err error
@@ -182,16 +236,32 @@ func runServer() error {
 
 	// Project routes
 	projectsGroup := e.Group("/projects")
-	project.NewProjectHandler(projectsGroup, projectService)
+	project.NewProjectHandler(projectsGroup, projectService, taskService, calendarService, settingsService)
 
 	// Task routes
 	tasksGroup := e.Group("/tasks")
-	task.NewTaskHandler(tasksGroup, taskService, projectService, calendarService)
+	task.NewTaskHandler(tasksGroup, taskService, activityService, projectService, calendarService, settingsService)
+
+	// Template routes
+	templatesGroup := e.Group("/templates")
+	tasktemplate.NewTemplateHandler(templatesGroup, templateService, taskService, projectService)
 
 	// Timeline routes
 	timelineGroup := e.Group("/timeline")
 	timeline.NewTaskHandler(timelineGroup, taskService, calendarService, projectService)
 
+	// Calendar routes
+	calendarGroup := e.Group("/calendar")
+	cal.NewCalendarHandler(calendarGroup, calendarService, taskService, projectService)
+
+	// Calendar feed routes, read by calendar apps rather than the page
+	feedsGroup := e.Group("/feeds")
+	cal.NewFeedHandler(feedsGroup, settingsService, taskService, projectService)
+
+	// Settings routes
+	settingsGroup := e.Group("/settings")
+	settings.NewSettingsHandler(settingsGroup, settingsService, projectService)
+
 	// Component routes
 	componentsGroup := e.Group("/components")
 	home.NewComponentsService(componentsGroup)
@@ -199,6 +269,9 @@ func runServer() error {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
+	// Keep the local copy of the calendar fresh so views never wait on Google
+	go calendarService.RunSync(ctx, calendarSyncInterval)
+
 	// Start server
 	go func() {
 		if err := e.Start(fmt.Sprintf(":%d", port)); err != nil && err != http.ErrServerClosed {
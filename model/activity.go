@@ -0,0 +1,143 @@
+package model
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"iter"
+	"slices"
+	"time"
+)
+
+//go:generate go tool go-enum -type=ActivityKind
+
+type ActivityKind int
+
+const (
+	CommentActivity ActivityKind = iota
+	CreatedActivity
+	ScheduledActivity
+	UnscheduledActivity
+	CompletedActivity
+	ReopenedActivity
+	MovedProjectActivity
+	ScheduledAllDayActivity
+	StatusChangedActivity
+)
+
+// ActivityTimeFormat is the layout times are recorded in by activity events.
+const ActivityTimeFormat = time.RFC3339
+
+// Activity is an entry in a task's thread, either a comment written by the
+// user or an event recorded when the task changes.
+type Activity struct {
+	ID        string
+	TaskID    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Kind ActivityKind
+	Body string `form:"body"` // Text of a comment
+
+	From string // Previous value for events which change a field, e.g. a project ID
+	To   string // New value for events which change a field
+}
+
+// IsComment reports whether the activity was written by the user rather than
+// recorded by the system.
+func (a Activity) IsComment() bool {
+	return a.Kind == CommentActivity
+}
+
+// Edited reports whether a comment was changed after it was written.
+func (a Activity) Edited() bool {
+	return a.UpdatedAt.After(a.CreatedAt)
+}
+
+func (a Activity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"id":        a.ID,
+		"taskId":    a.TaskID,
+		"createdAt": a.CreatedAt,
+		"updatedAt": a.UpdatedAt,
+		"kind":      a.Kind.String(),
+		"body":      a.Body,
+		"from":      a.From,
+		"to":        a.To,
+	})
+}
+
+// Marshal serializes the Activity to bytes using encoding/gob
+func (a *Activity) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	err := encoder.Encode(a)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes bytes into the Activity using encoding/gob
+func (a *Activity) Unmarshal(data []byte) error {
+	buf := bytes.NewBuffer(data)
+	decoder := gob.NewDecoder(buf)
+	return decoder.Decode(a)
+}
+
+type ActivityList struct {
+	activities []Activity
+}
+
+func (al *ActivityList) Len() int {
+	return len(al.activities)
+}
+
+func (al *ActivityList) IsEmpty() bool {
+	return len(al.activities) == 0
+}
+
+func (al *ActivityList) All() iter.Seq[Activity] {
+	return slices.Values(al.activities)
+}
+
+func (al *ActivityList) Push(activity Activity) {
+	al.activities = append(al.activities, activity)
+}
+
+// Comments returns only the entries written by the user.
+func (al *ActivityList) Comments() []Activity {
+	var comments []Activity
+	for _, activity := range al.activities {
+		if activity.IsComment() {
+			comments = append(comments, activity)
+		}
+	}
+
+	return comments
+}
+
+func NewActivityList() *ActivityList {
+	return &ActivityList{
+		activities: make([]Activity, 0),
+	}
+}
+
+// ExportedTask is a task together with its comments as written by the export.
+type ExportedTask struct {
+	Task     Task
+	Comments []Activity
+}
+
+func (e ExportedTask) MarshalJSON() ([]byte, error) {
+	fields := e.Task.jsonFields()
+
+	comments := e.Comments
+	if comments == nil {
+		comments = []Activity{}
+	}
+
+	fields["comments"] = comments
+
+	return json.Marshal(fields)
+}
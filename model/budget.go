@@ -0,0 +1,129 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pleimann/camel-do/utils"
+)
+
+// WeekOf is the Monday to Monday week the time falls in, in the user's time
+// zone.
+func WeekOf(t time.Time) (time.Time, time.Time) {
+	start := StartOfWeek(t, time.Monday)
+
+	return start, start.AddDate(0, 0, 7)
+}
+
+// StartOfWeek is the midnight starting the week the time falls in, in the
+// user's time zone, for weeks starting on the weekday first. Times saved with
+// another offset, like those read back from the database, are put in the
+// user's zone before their weekday is looked at.
+func StartOfWeek(t time.Time, first time.Weekday) time.Time {
+	day := utils.StartOfDay(t)
+
+	// Weekday counts from Sunday
+	offset := (int(day.Weekday()) - int(first) + 7) % 7
+
+	return utils.StartOfDay(day.AddDate(0, 0, -offset))
+}
+
+// BudgetUsage is how much of a project's weekly time budget is taken up.
+type BudgetUsage struct {
+	Budget int32 // Minutes per week
+	Used   int32 // Minutes of scheduled tasks and matched events in the week
+}
+
+// Percent is the share of the budget used, which can go over 100.
+func (u BudgetUsage) Percent() int {
+	if u.Budget <= 0 {
+		return 0
+	}
+
+	return int(u.Used * 100 / u.Budget)
+}
+
+// Remaining is how many minutes of the budget are left, negative once it's exceeded.
+func (u BudgetUsage) Remaining() int32 {
+	return u.Budget - u.Used
+}
+
+func (u BudgetUsage) IsOver() bool {
+	return u.Budget > 0 && u.Used > u.Budget
+}
+
+// MatchEvent finds the project a calendar event counts towards: the one it is
+// linked to, or else the active project whose name appears in its title. The
+// longest name wins so "Website Redesign" beats "Website".
+func (pi *ProjectIndex) MatchEvent(event Event) string {
+	if event.ProjectID.Valid {
+		return event.ProjectID.String
+	}
+
+	title := strings.ToLower(event.Title.String)
+
+	var match Project
+	for project := range pi.Values() {
+		name := strings.ToLower(strings.TrimSpace(project.Name))
+
+		if name != "" && strings.Contains(title, name) && len(name) > len(match.Name) {
+			match = project
+		}
+	}
+
+	return match.ID
+}
+
+// BudgetUsages works out how much of each budgeted project's weekly budget the
+// scheduled tasks and calendar events take up. Work on sub-projects counts
+// towards their parents' budgets too.
+func BudgetUsages(projects *ProjectIndex, tasks *TaskList, events *EventList) map[string]BudgetUsage {
+	minutes := map[string]int{}
+
+	for task := range tasks.All() {
+		if task.ProjectID.Valid && task.StartTime.Valid && task.Status != Cancelled {
+			minutes[task.ProjectID.String] += int(task.Duration.Int32)
+		}
+	}
+
+	for event := range events.All() {
+		// Whole days away and declined meetings aren't time spent on a project
+		if event.AllDay.Bool || event.Declined {
+			continue
+		}
+
+		if projectID := projects.MatchEvent(event); projectID != "" {
+			minutes[projectID] += int(event.Duration.Int32)
+		}
+	}
+
+	used := projects.RollUp(minutes)
+
+	usages := map[string]BudgetUsage{}
+	for _, project := range projects.All() {
+		if project.WeeklyBudget.Valid && project.WeeklyBudget.Int32 > 0 {
+			usages[project.ID] = BudgetUsage{
+				Budget: project.WeeklyBudget.Int32,
+				Used:   int32(used[project.ID]),
+			}
+		}
+	}
+
+	return usages
+}
+
+// ExceededBudget finds the budget the project's work has gone over, its own or
+// that of a project it is nested in, checking the closest first.
+func ExceededBudget(projects *ProjectIndex, usages map[string]BudgetUsage, projectID string) (*Project, BudgetUsage, bool) {
+	lineage := projects.ancestors(projectID)
+	lineage = append(lineage, projectID)
+
+	for _, id := range slices.Backward(lineage) {
+		if usage, ok := usages[id]; ok && usage.IsOver() {
+			return projects.Get(id), usage, true
+		}
+	}
+
+	return nil, BudgetUsage{}, false
+}
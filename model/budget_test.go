@@ -0,0 +1,65 @@
+package model
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/pleimann/camel-do/utils"
+)
+
+func TestWeekOf(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utils.SetLocation(newYork)
+	t.Cleanup(func() { utils.SetLocation(nil) })
+
+	// Times read back from the database keep the offset they were saved with
+	saved := func(value string) time.Time {
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return at
+	}
+
+	tests := []struct {
+		name      string
+		t         time.Time
+		wantStart time.Time
+	}{
+		{
+			name:      "Sunday evening is the end of the week though it's Monday in UTC",
+			t:         saved("2025-03-10T02:30:00Z"),
+			wantStart: time.Date(2025, 3, 3, 0, 0, 0, 0, newYork),
+		},
+		{
+			name:      "Monday morning in Berlin is still Sunday",
+			t:         saved("2025-06-09T00:30:00+02:00"),
+			wantStart: time.Date(2025, 6, 2, 0, 0, 0, 0, newYork),
+		},
+		{
+			name:      "a week the clocks go back in",
+			t:         saved("2025-11-05T12:00:00-05:00"),
+			wantStart: time.Date(2025, 11, 3, 0, 0, 0, 0, newYork),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := WeekOf(tt.t)
+
+			if !start.Equal(tt.wantStart) {
+				t.Errorf("WeekOf() starts %s, want %s", start, tt.wantStart)
+			}
+
+			if wantEnd := tt.wantStart.AddDate(0, 0, 7); !end.Equal(wantEnd) {
+				t.Errorf("WeekOf() ends %s, want %s", end, wantEnd)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// CalendarSync is how fresh the local copy of the calendar is.
+type CalendarSync struct {
+	LastSynced time.Time // Zero until the first sync completes
+	Error      string    // Why the last attempt failed, empty when it succeeded
+}
+
+func (s CalendarSync) IsSynced() bool {
+	return !s.LastSynced.IsZero()
+}
+
+// IsStale tells when the calendar hasn't synced for so long that the events
+// shown may well be out of date.
+func (s CalendarSync) IsStale(now time.Time) bool {
+	return !s.IsSynced() || now.Sub(s.LastSynced) > 30*time.Minute
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:generate go tool go-enum -type=FieldType
+
+type FieldType int
+
+const (
+	TextField FieldType = iota
+	NumberField
+	DateField
+	SelectField
+	URLField
+)
+
+// Label is the name of the field type shown to users.
+func (t FieldType) Label() string {
+	return strings.TrimSuffix(t.String(), "Field")
+}
+
+// CustomFieldDateFormat is the layout date custom field values are stored in.
+const CustomFieldDateFormat = "2006-01-02"
+
+// CustomField is a typed field a project defines for all of its tasks.
+type CustomField struct {
+	ID       string
+	Name     string
+	Type     FieldType
+	Options  []string // Allowed values of a SelectField
+	Required bool
+}
+
+// InputName is the name of the form input holding a task's value of the field.
+func (f CustomField) InputName() string {
+	return "field." + f.ID
+}
+
+// Validate checks a raw form value against the field's type. Empty values are
+// only rejected when the field is required.
+func (f CustomField) Validate(value string) error {
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		if f.Required {
+			return fmt.Errorf("%s is required", f.Name)
+		}
+
+		return nil
+	}
+
+	switch f.Type {
+	case NumberField:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%s must be a number", f.Name)
+		}
+
+	case DateField:
+		if _, err := time.Parse(CustomFieldDateFormat, value); err != nil {
+			return fmt.Errorf("%s must be a date (YYYY-MM-DD)", f.Name)
+		}
+
+	case SelectField:
+		if !slices.Contains(f.Options, value) {
+			return fmt.Errorf("%s must be one of %s", f.Name, strings.Join(f.Options, ", "))
+		}
+
+	case URLField:
+		if u, err := url.ParseRequestURI(value); err != nil || u.Host == "" {
+			return fmt.Errorf("%s must be a URL", f.Name)
+		}
+	}
+
+	return nil
+}
+
+// Compare orders two values of this field according to its type. Empty values
+// sort after everything else.
+func (f CustomField) Compare(a, b string) int {
+	if a == "" || b == "" {
+		return cmp.Compare(b, a)
+	}
+
+	switch f.Type {
+	case NumberField:
+		na, errA := strconv.ParseFloat(a, 64)
+		nb, errB := strconv.ParseFloat(b, 64)
+
+		if errA == nil && errB == nil {
+			return cmp.Compare(na, nb)
+		}
+
+	case SelectField:
+		// Select values sort in the order the options were defined
+		if ia, ib := slices.Index(f.Options, a), slices.Index(f.Options, b); ia >= 0 && ib >= 0 {
+			return cmp.Compare(ia, ib)
+		}
+	}
+
+	// Dates are stored as YYYY-MM-DD so they sort lexically
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
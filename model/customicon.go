@@ -0,0 +1,174 @@
+package model
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxCustomIconSize caps the size of an uploaded SVG icon.
+const MaxCustomIconSize = 64 * 1024
+
+// CustomIcon is an SVG icon uploaded to identify a project.
+type CustomIcon struct {
+	ID        string
+	CreatedAt time.Time
+
+	Name string // File name the icon was uploaded as
+	SVG  []byte // Sanitized markup
+}
+
+// Marshal serializes the CustomIcon to bytes using encoding/gob
+func (i *CustomIcon) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	if err := encoder.Encode(i); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes bytes to a CustomIcon using encoding/gob
+func (i *CustomIcon) Unmarshal(data []byte) error {
+	buf := bytes.NewBuffer(data)
+	decoder := gob.NewDecoder(buf)
+	return decoder.Decode(i)
+}
+
+const svgNamespace = "http://www.w3.org/2000/svg"
+
+// svgElements are the drawing elements an icon may use. Anything else, like
+// scripts, styles, foreign objects or links, is dropped along with its content.
+var svgElements = []string{
+	"svg", "g", "defs", "symbol", "use", "title", "desc",
+	"path", "circle", "ellipse", "line", "polyline", "polygon", "rect",
+	"linearGradient", "radialGradient", "stop", "clipPath", "mask",
+}
+
+// svgAttributes are the presentation attributes an icon may use. Event
+// handlers and styles are dropped.
+var svgAttributes = []string{
+	"id", "class", "viewBox", "width", "height", "preserveAspectRatio", "version",
+	"x", "y", "x1", "y1", "x2", "y2", "cx", "cy", "r", "rx", "ry", "fx", "fy", "d", "points", "transform",
+	"fill", "fill-opacity", "fill-rule", "stroke", "stroke-width", "stroke-linecap", "stroke-linejoin",
+	"stroke-opacity", "stroke-dasharray", "stroke-dashoffset", "stroke-miterlimit", "opacity",
+	"clip-path", "clip-rule", "mask", "offset", "stop-color", "stop-opacity",
+	"gradientUnits", "gradientTransform", "spreadMethod", "clipPathUnits", "maskUnits", "href",
+}
+
+// SanitizeSVG rewrites an uploaded SVG keeping only the elements and
+// attributes needed to draw it, so it can't run scripts or load anything.
+func SanitizeSVG(data []byte) ([]byte, error) {
+	if len(data) > MaxCustomIconSize {
+		return nil, fmt.Errorf("icons can be at most %d KB", MaxCustomIconSize/1024)
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+
+	var out bytes.Buffer
+	var open []string
+	skip := 0
+	sawRoot := false
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("reading SVG: %w", err)
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if skip > 0 || !isSVGElement(token.Name) {
+				skip++
+				continue
+			}
+
+			if !sawRoot {
+				if token.Name.Local != "svg" {
+					return nil, errors.New("the file isn't an SVG image")
+				}
+
+				sawRoot = true
+
+			} else if len(open) == 0 {
+				return nil, errors.New("an SVG image has a single root element")
+			}
+
+			out.WriteString("<" + token.Name.Local)
+			if len(open) == 0 {
+				out.WriteString(` xmlns="` + svgNamespace + `"`)
+			}
+
+			for _, attr := range token.Attr {
+				if isSafeSVGAttribute(attr) {
+					out.WriteString(" " + attr.Name.Local + `="`)
+					xml.EscapeText(&out, []byte(attr.Value))
+					out.WriteString(`"`)
+				}
+			}
+
+			out.WriteString(">")
+			open = append(open, token.Name.Local)
+
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+
+			out.WriteString("</" + open[len(open)-1] + ">")
+			open = open[:len(open)-1]
+
+		case xml.CharData:
+			if skip == 0 && len(open) > 0 && slices.Contains([]string{"title", "desc"}, open[len(open)-1]) {
+				xml.EscapeText(&out, token)
+			}
+		}
+	}
+
+	if !sawRoot {
+		return nil, errors.New("the file isn't an SVG image")
+	}
+
+	return out.Bytes(), nil
+}
+
+func isSVGElement(name xml.Name) bool {
+	return (name.Space == "" || name.Space == svgNamespace) && slices.Contains(svgElements, name.Local)
+}
+
+func isSafeSVGAttribute(attr xml.Attr) bool {
+	// Namespaced attributes are only kept for xlink:href
+	if attr.Name.Space != "" && attr.Name.Local != "href" {
+		return false
+	}
+
+	if !slices.Contains(svgAttributes, attr.Name.Local) {
+		return false
+	}
+
+	value := strings.ToLower(strings.TrimSpace(attr.Value))
+
+	// References may only point inside the icon
+	if attr.Name.Local == "href" {
+		return strings.HasPrefix(value, "#")
+	}
+
+	if strings.Contains(value, "url(") {
+		return strings.HasPrefix(strings.ReplaceAll(value, " ", ""), "url(#") && strings.Count(value, "url(") == 1
+	}
+
+	return true
+}
@@ -1,18 +1,30 @@
 package model
 
 import (
+	"bytes"
 	"cmp"
+	"encoding/gob"
 	"iter"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/guregu/null/v6/zero"
+	"github.com/pleimann/camel-do/utils"
 )
 
 type Event struct {
 	Task
 
 	ConferenceData string
+
+	CalendarID string // Calendar the event is on
+	Color      Color  // Color of its calendar, for events without a project
+	TaskID     string // Task the event was published from, if any
+	Declined   bool   // The account's owner said they won't go, so the time stays free
+	Free       bool   // Shown as free rather than busy, like reminders and holidays
+
+	Attendees []string // Names, or addresses when there are none, of the others invited
 }
 
 func NewEvent(
@@ -46,6 +58,72 @@ func NewEvent(
 	return event
 }
 
+// Busy tells whether the event takes up its time, so nothing else should be
+// scheduled then. Events taking whole days say where the user is rather than
+// what they're doing.
+func (e Event) Busy() bool {
+	return !e.Declined && !e.Free && !e.AllDay.Bool
+}
+
+// FollowUpTask is a new task for what came out of the event, starting from
+// its notes and who was invited, linked back to the event.
+func (e Event) FollowUpTask() Task {
+	description := e.Description.String
+	if len(e.Attendees) > 0 {
+		description = strings.TrimSpace(description + "\n\nAttendees: " + strings.Join(e.Attendees, ", "))
+	}
+
+	return Task{
+		Title:         zero.StringFrom("Follow up on " + e.Title.String),
+		Description:   zero.NewString(description, description != ""),
+		SourceEventID: zero.StringFrom(e.ID),
+	}
+}
+
+// Marshal serializes the Event to bytes using encoding/gob
+func (e *Event) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	if err := encoder.Encode(e); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes bytes into the Event using encoding/gob. All day
+// events are put back on their dates in the user's time zone, which may have
+// changed since they were stored.
+func (e *Event) Unmarshal(data []byte) error {
+	buf := bytes.NewBuffer(data)
+	decoder := gob.NewDecoder(buf)
+	if err := decoder.Decode(e); err != nil {
+		return err
+	}
+
+	if e.AllDay.Bool && e.StartTime.Valid {
+		start := onDateOf(e.StartTime.Time)
+		e.StartTime = zero.TimeFrom(start)
+
+		if e.EndTime.Valid {
+			end := onDateOf(e.EndTime.Time)
+
+			e.EndTime = zero.TimeFrom(end)
+			e.Duration = zero.Int32From(int32(end.Sub(start).Minutes()))
+		}
+	}
+
+	return nil
+}
+
+// onDateOf is the start of the day t is on where it was written, in the
+// user's time zone.
+func onDateOf(t time.Time) time.Time {
+	year, month, day := t.Date()
+
+	return utils.StartOfDay(time.Date(year, month, day, 12, 0, 0, 0, utils.Location()))
+}
+
 type EventList struct {
 	events []Event
 }
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/guregu/null/v6/zero"
+	"github.com/pleimann/camel-do/utils"
+)
+
+func TestEventUnmarshalKeepsAllDayDates(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { utils.SetLocation(nil) })
+
+	// Cached while the user was in New York
+	utils.SetLocation(newYork)
+	event := Event{Task: Task{
+		ID:        "holiday",
+		AllDay:    zero.BoolFrom(true),
+		StartTime: zero.TimeFrom(time.Date(2025, 7, 4, 0, 0, 0, 0, newYork)),
+		EndTime:   zero.TimeFrom(time.Date(2025, 7, 5, 0, 0, 0, 0, newYork)),
+	}}
+
+	data, err := event.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read after moving to Tokyo
+	utils.SetLocation(tokyo)
+	var read Event
+	if err := read.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+
+	wantStart := time.Date(2025, 7, 4, 0, 0, 0, 0, tokyo)
+	wantEnd := time.Date(2025, 7, 5, 0, 0, 0, 0, tokyo)
+
+	if !read.StartTime.Time.Equal(wantStart) || !read.EndTime.Time.Equal(wantEnd) {
+		t.Errorf("read as %s to %s, want %s to %s", read.StartTime.Time, read.EndTime.Time, wantStart, wantEnd)
+	}
+
+	if read.Duration.Int32 != 24*60 {
+		t.Errorf("duration = %d minutes, want a day", read.Duration.Int32)
+	}
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"crypto/subtle"
+	"time"
+)
+
+// Feed is a secret link calendar apps can subscribe to, so scheduled tasks
+// show up in them without anything syncing back.
+type Feed struct {
+	ID        string
+	Token     string // Secret in the feed's link, anyone with the link can read the feed
+	ProjectID string // Project whose tasks, along with those of its sub-projects, are in the feed, all tasks when empty
+	CreatedAt time.Time
+}
+
+// FeedByToken finds the feed the token was handed out for, nil when it never
+// was or the feed was revoked.
+func (s *Settings) FeedByToken(token string) *Feed {
+	for i := range s.Feeds {
+		// Comparing in constant time keeps the tokens from being guessed a
+		// character at a time
+		if subtle.ConstantTimeCompare([]byte(s.Feeds[i].Token), []byte(token)) == 1 {
+			return &s.Feeds[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HexColor is a color picked freely rather than from the presets, e.g. #1e90ff.
+type HexColor string
+
+var hexColorPattern = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// ParseHexColor reads a #rgb or #rrggbb color, normalizing it to lower case
+// #rrggbb.
+func ParseHexColor(s string) (HexColor, error) {
+	match := hexColorPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return "", fmt.Errorf("%q is not a hex color", s)
+	}
+
+	digits := strings.ToLower(match[1])
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+
+	return HexColor("#" + digits), nil
+}
+
+// RGB splits the color into its red, green and blue channels.
+func (c HexColor) RGB() (uint8, uint8, uint8) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(string(c), "#"), 16, 32)
+	if err != nil {
+		return 0, 0, 0
+	}
+
+	return uint8(value >> 16), uint8(value >> 8), uint8(value)
+}
+
+// Luminance is the color's relative luminance as WCAG 2 defines it, from 0 for
+// black to 1 for white.
+func (c HexColor) Luminance() float64 {
+	r, g, b := c.RGB()
+
+	linear := func(channel uint8) float64 {
+		v := float64(channel) / 255
+		if v <= 0.04045 {
+			return v / 12.92
+		}
+
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+
+	return 0.2126*linear(r) + 0.7152*linear(g) + 0.0722*linear(b)
+}
+
+// ContrastRatio is the WCAG 2 contrast ratio between two colors, from 1 for
+// the same color to 21 for black on white.
+func ContrastRatio(a HexColor, b HexColor) float64 {
+	la, lb := a.Luminance(), b.Luminance()
+
+	return (max(la, lb) + 0.05) / (min(la, lb) + 0.05)
+}
+
+const (
+	Black HexColor = "#000000"
+	White HexColor = "#ffffff"
+)
+
+// TextColor is black or white, whichever is easier to read on the color.
+func (c HexColor) TextColor() HexColor {
+	if ContrastRatio(c, Black) >= ContrastRatio(c, White) {
+		return Black
+	}
+
+	return White
+}
+
+// presetHex are the shades of the preset colors project chips are drawn in.
+var presetHex = map[Color]HexColor{
+	Zinc:    "#e4e4e7",
+	Red:     "#fecaca",
+	Orange:  "#fed7aa",
+	Amber:   "#fde68a",
+	Yellow:  "#fef08a",
+	Lime:    "#d9f99d",
+	Green:   "#bbf7d0",
+	Emerald: "#a7f3d0",
+	Teal:    "#99f6e4",
+	Cyan:    "#a5f3fc",
+	Sky:     "#bae6fd",
+	Violet:  "#ddd6fe",
+	Purple:  "#e9d5ff",
+	Fuchsia: "#f5d0fe",
+	Pink:    "#fbcfe8",
+	Rose:    "#fecdd3",
+}
+
+// Hex is the shade the preset is drawn in, e.g. to seed the custom color picker.
+func (c Color) Hex() HexColor {
+	return presetHex[c]
+}
+
+// NearestColor is the preset closest to the color, e.g. to pick a preset for a
+// calendar colored elsewhere.
+func NearestColor(c HexColor) Color {
+	r, g, b := c.RGB()
+
+	nearest, shortest := Zinc, math.MaxFloat64
+	for _, preset := range ColorValues() {
+		pr, pg, pb := preset.Hex().RGB()
+
+		distance := math.Pow(float64(r)-float64(pr), 2) + math.Pow(float64(g)-float64(pg), 2) + math.Pow(float64(b)-float64(pb), 2)
+		if distance < shortest {
+			nearest, shortest = preset, distance
+		}
+	}
+
+	return nearest
+}
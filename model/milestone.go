@@ -0,0 +1,146 @@
+package model
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// MilestoneDateFormat is the layout milestone target dates are entered in.
+const MilestoneDateFormat = "2006-01-02"
+
+// Milestone is a dated goal, such as a beta or a launch, a project's tasks can
+// be grouped under.
+type Milestone struct {
+	ID         string
+	Name       string
+	TargetDate time.Time // Day the milestone is due, its work has to be done by the end of it
+}
+
+// Due is the end of the milestone's target day.
+func (m Milestone) Due() time.Time {
+	year, month, day := m.TargetDate.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, m.TargetDate.Location())
+}
+
+// Milestone finds one of the project's milestones by its ID.
+func (p *Project) Milestone(id string) (Milestone, bool) {
+	for _, milestone := range p.Milestones {
+		if milestone.ID == id {
+			return milestone, true
+		}
+	}
+
+	return Milestone{}, false
+}
+
+// MilestoneProgress tracks how much of a milestone's work is done and whether
+// what's left fits before its target date.
+type MilestoneProgress struct {
+	Milestone
+
+	Done             int   // Tasks which were finished
+	Total            int   // Tasks which weren't cancelled
+	RemainingMinutes int32 // Estimated duration of the open tasks
+
+	AtRisk bool // Open work doesn't fit in the working hours left before the target date
+}
+
+// Progress is the share of the milestone's tasks which are done, in percent.
+func (mp MilestoneProgress) Progress() int {
+	if mp.Total == 0 {
+		return 0
+	}
+
+	return mp.Done * 100 / mp.Total
+}
+
+// SummarizeMilestones works out the progress of each of the project's
+// milestones from its tasks, soonest target date first. Open work is weighed
+// against the working hours left.
+func SummarizeMilestones(project *Project, tasks *TaskList, now time.Time, hours WorkingHours) []MilestoneProgress {
+	progress := make([]MilestoneProgress, 0, len(project.Milestones))
+	byID := map[string]int{}
+
+	for _, milestone := range project.Milestones {
+		byID[milestone.ID] = len(progress)
+		progress = append(progress, MilestoneProgress{Milestone: milestone})
+	}
+
+	// Open tasks scheduled to start only after the milestone is due put it at risk too
+	late := map[string]bool{}
+
+	for task := range tasks.All() {
+		i, ok := byID[task.MilestoneID.String]
+		if !ok || task.ParentID.Valid || task.Status == Cancelled {
+			continue
+		}
+
+		progress[i].Total++
+
+		if task.Status == Done {
+			progress[i].Done++
+			continue
+		}
+
+		progress[i].RemainingMinutes += task.Duration.Int32
+
+		if task.StartTime.Valid && !task.StartTime.Time.Before(progress[i].Due()) {
+			late[task.MilestoneID.String] = true
+		}
+	}
+
+	for i := range progress {
+		open := progress[i].Done < progress[i].Total
+
+		progress[i].AtRisk = open && (late[progress[i].ID] ||
+			int(progress[i].RemainingMinutes) > WorkingMinutesBetween(now, progress[i].Due(), hours))
+	}
+
+	slices.SortStableFunc(progress, func(a, b MilestoneProgress) int {
+		return cmp.Compare(a.TargetDate.Unix(), b.TargetDate.Unix())
+	})
+
+	return progress
+}
+
+// WorkingMinutesBetween counts the minutes of the working hours which fall
+// between from and to, in from's location. Days off don't count.
+func WorkingMinutesBetween(from time.Time, to time.Time, hours WorkingHours) int {
+	minutes := 0
+
+	// Days are picked at noon, some zones skip midnight when the clocks
+	// change. Hours after to are cut off, so going a day too far adds nothing.
+	year, month, date := from.Date()
+	for day := time.Date(year, month, date, 12, 0, 0, 0, from.Location()); day.Before(to.AddDate(0, 0, 1)); day = day.AddDate(0, 0, 1) {
+		dayStart, dayEnd, ok := hours.On(day)
+		if !ok {
+			continue
+		}
+
+		start := later(dayStart, from)
+		end := earlier(dayEnd, to)
+
+		if end.After(start) {
+			minutes += int(end.Sub(start).Minutes())
+		}
+	}
+
+	return minutes
+}
+
+func later(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
+
+func earlier(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkingMinutesBetween(t *testing.T) {
+	// Friday June 6th 2025
+	at := func(day int, hour int) time.Time {
+		return time.Date(2025, 6, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		from time.Time
+		to   time.Time
+		want int
+	}{
+		{name: "the rest of the working day", from: at(6, 16), to: at(6, 20), want: 60},
+		{name: "the weekend isn't worked", from: at(7, 8), to: at(9, 0), want: 0},
+		{name: "over the weekend", from: at(6, 16), to: at(10, 0), want: 60 + 8*60},
+		{name: "up to the middle of a day", from: at(9, 0), to: at(9, 12), want: 3 * 60},
+		{name: "after work ends", from: at(6, 18), to: at(7, 0), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WorkingMinutesBetween(tt.from, tt.to, DefaultWorkingHours); got != tt.want {
+				t.Errorf("WorkingMinutesBetween() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
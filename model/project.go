@@ -2,10 +2,17 @@ package model
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/gob"
+	"errors"
+	"fmt"
 	"iter"
 	"maps"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/guregu/null/v6/zero"
 )
 
 // Project represents a project in the task tracking application.
@@ -17,6 +24,46 @@ type Project struct {
 	Name  string `form:"projectName" jet:"column:name"`
 	Color Color  `form:"color,default:Zinc" jet:"column:color"`  // Color of the task
 	Icon  Icon   `form:"icon,default:Unknown" jet:"column:icon"` // Icon to identify project
+
+	HexColor     HexColor    `form:"hexColor"`     // Custom color used instead of the preset
+	CustomIconID zero.String `form:"customIconId"` // Uploaded icon used instead of the preset
+
+	ParentID zero.String `form:"parentId"` // Area or project this one is nested in
+
+	CustomFields []CustomField // Typed fields tasks in this project carry
+	Milestones   []Milestone   // Dated goals the project's tasks are grouped under
+
+	Defaults     TaskDefaults // Values new tasks in the project start with
+	WeeklyBudget zero.Int32   // Minutes per week the project is meant to take
+
+	Archived   bool      // Finished projects are kept for their history but hidden from selectors
+	ArchivedAt zero.Time // When the project was archived
+
+	Rank   int  // Position among its sibling projects set by dragging them around
+	Pinned bool // Favorite projects come first in the task dialog
+}
+
+// CustomField finds one of the project's custom fields by its ID.
+func (p *Project) CustomField(id string) (CustomField, bool) {
+	for _, field := range p.CustomFields {
+		if field.ID == id {
+			return field, true
+		}
+	}
+
+	return CustomField{}, false
+}
+
+// ValidateCustomValues checks task values against the project's custom fields.
+func (p *Project) ValidateCustomValues(values map[string]string) error {
+	var errs []error
+	for _, field := range p.CustomFields {
+		if err := field.Validate(values[field.ID]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
 // Marshal serializes the Project to bytes using encoding/gob
@@ -37,7 +84,6 @@ func (p *Project) Unmarshal(data []byte) error {
 	return decoder.Decode(p)
 }
 
-
 type ProjectIndex struct {
 	projects map[string]Project
 }
@@ -52,8 +98,49 @@ func (pi *ProjectIndex) All() iter.Seq2[string, Project] {
 	return maps.All(pi.projects)
 }
 
+// Values iterates the projects which aren't archived in their manual order,
+// e.g. for selectors.
 func (pi *ProjectIndex) Values() iter.Seq[Project] {
-	return maps.Values(pi.projects)
+	return func(yield func(Project) bool) {
+		for _, project := range pi.sorted() {
+			if !project.Archived && !yield(project) {
+				return
+			}
+		}
+	}
+}
+
+// Pinned returns the favorite projects which aren't archived in their manual order.
+func (pi *ProjectIndex) Pinned() []Project {
+	var pinned []Project
+	for project := range pi.Values() {
+		if project.Pinned {
+			pinned = append(pinned, project)
+		}
+	}
+
+	return pinned
+}
+
+// sorted lists every project by rank, projects which were never moved by name.
+func (pi *ProjectIndex) sorted() []Project {
+	projects := slices.Collect(maps.Values(pi.projects))
+
+	slices.SortFunc(projects, func(a, b Project) int {
+		if n := cmp.Compare(a.Rank, b.Rank); n != 0 {
+			return n
+		}
+
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	return projects
+}
+
+// IsArchived reports whether the project with the ID is in the index and archived.
+func (pi *ProjectIndex) IsArchived(id string) bool {
+	project, ok := pi.projects[id]
+	return ok && project.Archived
 }
 
 func (pi *ProjectIndex) Add(project Project) {
@@ -64,3 +151,195 @@ func (pi *ProjectIndex) Get(id string) *Project {
 	p := pi.projects[id]
 	return &p
 }
+
+// ErrProjectCycle is returned when a project would be nested in itself.
+var ErrProjectCycle = errors.New("a project can't be nested in itself or one of its sub-projects")
+
+// ErrProjectArchived is returned when a project would be moved next to an
+// archived one.
+var ErrProjectArchived = errors.New("a project can't be moved next to an archived project")
+
+// Children returns the projects nested directly in the parent in their manual
+// order. An empty parent ID returns the top level projects.
+func (pi *ProjectIndex) Children(parentID string) []Project {
+	var children []Project
+	for project := range pi.Values() {
+		if pi.parentOf(project) == parentID {
+			children = append(children, project)
+		}
+	}
+
+	return children
+}
+
+// Move places the project next to the target among the target's siblings,
+// nesting it in the target's parent. It lands after the target when it was
+// its sibling and came before it, as when it is dragged down the list, and
+// before it otherwise. The reordered siblings are returned with their new ranks.
+func (pi *ProjectIndex) Move(id string, targetID string) ([]Project, error) {
+	project, ok := pi.projects[id]
+	if !ok {
+		return nil, fmt.Errorf("project %s not found", id)
+	}
+
+	target, ok := pi.projects[targetID]
+	if !ok {
+		return nil, fmt.Errorf("project %s not found", targetID)
+	}
+
+	if id == targetID {
+		return nil, nil
+	}
+
+	if target.Archived {
+		return nil, ErrProjectArchived
+	}
+
+	// Projects in an archived parent are listed at the top level, so that's
+	// where the moved one goes too rather than out of sight
+	parentID := pi.parentOf(target)
+	if parentID == id || (parentID != "" && pi.IsDescendant(parentID, id)) {
+		return nil, ErrProjectCycle
+	}
+
+	siblings := pi.Children(parentID)
+
+	from := slices.IndexFunc(siblings, func(p Project) bool { return p.ID == id })
+	if from >= 0 {
+		siblings = slices.Delete(siblings, from, from+1)
+	}
+
+	to := slices.IndexFunc(siblings, func(p Project) bool { return p.ID == targetID })
+	if from >= 0 && from <= to {
+		to++
+	}
+
+	project.ParentID = zero.NewString(parentID, parentID != "")
+	siblings = slices.Insert(siblings, to, project)
+
+	for i := range siblings {
+		siblings[i].Rank = i
+		pi.projects[siblings[i].ID] = siblings[i]
+	}
+
+	return siblings, nil
+}
+
+// Tree walks the projects which aren't archived depth first, yielding how deep
+// each one is nested.
+func (pi *ProjectIndex) Tree() iter.Seq2[int, Project] {
+	return func(yield func(int, Project) bool) {
+		var walk func(parentID string, depth int) bool
+		walk = func(parentID string, depth int) bool {
+			for _, project := range pi.Children(parentID) {
+				if !yield(depth, project) || !walk(project.ID, depth+1) {
+					return false
+				}
+			}
+
+			return true
+		}
+
+		walk("", 0)
+	}
+}
+
+// IsDescendant reports whether the project is nested, at any depth, in the
+// ancestor.
+func (pi *ProjectIndex) IsDescendant(id string, ancestorID string) bool {
+	// Bounded by the number of projects in case stored data has a cycle
+	for range len(pi.projects) {
+		project, ok := pi.projects[id]
+		if !ok || !project.ParentID.Valid {
+			return false
+		}
+
+		if project.ParentID.String == ancestorID {
+			return true
+		}
+
+		id = project.ParentID.String
+	}
+
+	return false
+}
+
+// Descendants returns the IDs of every project nested in the project.
+func (pi *ProjectIndex) Descendants(id string) []string {
+	var descendants []string
+	for projectID := range pi.projects {
+		if pi.IsDescendant(projectID, id) {
+			descendants = append(descendants, projectID)
+		}
+	}
+
+	return descendants
+}
+
+// Path names the project along with the projects it's nested in.
+func (pi *ProjectIndex) Path(id string) string {
+	var names []string
+	for _, ancestorID := range pi.ancestors(id) {
+		names = append(names, pi.projects[ancestorID].Name)
+	}
+
+	return strings.Join(append(names, pi.projects[id].Name), " › ")
+}
+
+// RollUp adds the counts of each project's descendants to its own count.
+func (pi *ProjectIndex) RollUp(counts map[string]int) map[string]int {
+	totals := make(map[string]int, len(counts))
+	for projectID, count := range counts {
+		totals[projectID] += count
+
+		for _, ancestorID := range pi.ancestors(projectID) {
+			totals[ancestorID] += count
+		}
+	}
+
+	return totals
+}
+
+// ancestors lists the projects the project is nested in, outermost first.
+func (pi *ProjectIndex) ancestors(id string) []string {
+	var ancestors []string
+	for range len(pi.projects) {
+		project, ok := pi.projects[id]
+		if !ok || !project.ParentID.Valid {
+			break
+		}
+
+		id = project.ParentID.String
+		if _, ok := pi.projects[id]; !ok || slices.Contains(ancestors, id) {
+			break
+		}
+
+		ancestors = append(ancestors, id)
+	}
+
+	slices.Reverse(ancestors)
+
+	return ancestors
+}
+
+// parentOf is the ID of the project's parent, empty when it's at the top level
+// because it has none or its parent isn't listed.
+func (pi *ProjectIndex) parentOf(project Project) string {
+	parent, ok := pi.projects[project.ParentID.String]
+	if !ok || parent.Archived {
+		return ""
+	}
+
+	return parent.ID
+}
+
+// CustomField finds a custom field by ID across all projects in the index.
+func (pi *ProjectIndex) CustomField(id string) (CustomField, bool) {
+	for _, project := range pi.projects {
+		if field, ok := project.CustomField(id); ok {
+			return field, true
+		}
+	}
+
+	return CustomField{}, false
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/guregu/null/v6/zero"
+)
+
+func TestProjectIndexMove(t *testing.T) {
+	newIndex := func() *ProjectIndex {
+		pi := NewProjectIndex()
+		pi.Add(Project{ID: "work", Rank: 0})
+		pi.Add(Project{ID: "old", Rank: 1, Archived: true})
+		pi.Add(Project{ID: "left", Rank: 0, ParentID: zero.StringFrom("old")})
+		pi.Add(Project{ID: "home", Rank: 2})
+
+		return pi
+	}
+
+	tests := []struct {
+		name       string
+		id         string
+		targetID   string
+		wantErr    error
+		wantParent string
+	}{
+		{name: "next to a top level project", id: "home", targetID: "work"},
+		{name: "next to an archived project", id: "home", targetID: "old", wantErr: ErrProjectArchived},
+		{name: "next to one left in an archived parent goes to the top level", id: "home", targetID: "left"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pi := newIndex()
+
+			_, err := pi.Move(tt.id, tt.targetID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Move() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if parent := pi.Get(tt.id).ParentID.String; parent != tt.wantParent {
+				t.Errorf("parent = %q, want %q", parent, tt.wantParent)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"cmp"
+	"time"
+)
+
+// ProjectSummary splits a project's tasks by where they stand and tracks how
+// far along the project is.
+type ProjectSummary struct {
+	Open      *TaskList // Open tasks waiting in the backlog
+	Scheduled *TaskList // Open tasks with a start time, soonest first
+	Closed    *TaskList // Done or cancelled tasks, most recently closed first
+
+	Done             int   // Tasks which were finished
+	Total            int   // Tasks which weren't cancelled
+	RemainingMinutes int32 // Estimated duration of the open tasks
+
+	Next *Task // Open task scheduled soonest from now, if any
+
+	Milestones []MilestoneProgress // Progress towards each milestone, soonest first
+}
+
+// SummarizeProject groups the tasks of a project. Subtasks are left out as
+// they are tracked on their parent task. Milestones are weighed against the
+// working hours.
+func SummarizeProject(project *Project, tasks *TaskList, now time.Time, hours WorkingHours) ProjectSummary {
+	summary := ProjectSummary{
+		Open:       NewTaskList(),
+		Scheduled:  NewTaskList(),
+		Closed:     NewTaskList(),
+		Milestones: SummarizeMilestones(project, tasks, now, hours),
+	}
+
+	for task := range tasks.All() {
+		if task.ParentID.Valid {
+			continue
+		}
+
+		if task.Status != Cancelled {
+			summary.Total++
+		}
+
+		switch {
+		case task.Status.IsClosed():
+			if task.Status == Done {
+				summary.Done++
+			}
+
+			summary.Closed.Push(task)
+
+		case task.StartTime.Valid:
+			summary.RemainingMinutes += task.Duration.Int32
+			summary.Scheduled.Push(task)
+
+			if !task.End().Before(now) && (summary.Next == nil || task.StartTime.Time.Before(summary.Next.StartTime.Time)) {
+				summary.Next = &task
+			}
+
+		default:
+			summary.RemainingMinutes += task.Duration.Int32
+			summary.Open.Push(task)
+		}
+	}
+
+	summary.Open.SortFunc(func(a, b Task) int {
+		return cmp.Compare(a.Rank.Int32, b.Rank.Int32)
+	})
+
+	summary.Scheduled.Sort()
+
+	summary.Closed.SortFunc(func(a, b Task) int {
+		return b.StatusSince().Compare(a.StatusSince())
+	})
+
+	return summary
+}
+
+// Progress is the share of the project's tasks which are done, in percent.
+func (s ProjectSummary) Progress() int {
+	if s.Total == 0 {
+		return 0
+	}
+
+	return s.Done * 100 / s.Total
+}
@@ -0,0 +1,150 @@
+package model
+
+import (
+	"bytes"
+	"encoding/gob"
+	"slices"
+	"time"
+)
+
+// Settings are the preferences which apply across the whole app.
+type Settings struct {
+	Calendars  []CalendarSettings // Calendars of the accounts in the order they list them
+	Publishing PublishSettings
+	CalDAV     CalDAVAccount
+	Feeds      []Feed // Links calendar apps subscribe to for the scheduled tasks
+
+	WorkingHours WorkingHours // When tasks are fit in without a time being picked, the default ones until set
+	TimeZone     string       // IANA name of the zone the user's days are in, like Europe/Berlin, the machine's until set
+}
+
+// Location is the time zone the user's days are in, the machine's until one is
+// set or when the one set is no longer known.
+func (s *Settings) Location() *time.Location {
+	if s.TimeZone == "" {
+		return time.Local
+	}
+
+	location, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+
+	return location
+}
+
+// CalDAVAccount is a CalDAV server, like Nextcloud, Fastmail or Radicale, to
+// show the calendars of as well as Google's.
+//
+// The password is kept in plain text in the local database, as CalDAV servers
+// need it for every request, so an app password is the better choice.
+type CalDAVAccount struct {
+	URL      string `form:"caldavUrl"` // Any URL of the account, its calendars are found from there
+	Username string `form:"caldavUsername"`
+	Password string `form:"caldavPassword"`
+}
+
+func (a CalDAVAccount) IsConfigured() bool {
+	return a.URL != ""
+}
+
+// Redacted is the account without its password, for showing back to users.
+func (a CalDAVAccount) Redacted() CalDAVAccount {
+	a.Password = ""
+
+	return a
+}
+
+// PublishSettings are how scheduled tasks are mirrored as calendar events.
+type PublishSettings struct {
+	CalendarID string `form:"publishCalendarId"` // Calendar the events go on, none when tasks aren't published
+	Busy       bool   `form:"publishBusy"`       // Whether others see the time of open tasks as busy
+}
+
+func (p PublishSettings) IsEnabled() bool {
+	return p.CalendarID != ""
+}
+
+// CalendarSettings are how one of the account's calendars shows on the timeline.
+type CalendarSettings struct {
+	ID       string
+	Provider string // Calendar service the calendar is synced from
+	Name     string
+	Primary  bool
+	Writable bool // Events can be added to it, so tasks can be published there
+
+	Visible bool  `form:"visible"` // Events of hidden calendars are left off the timeline
+	Color   Color `form:"color"`   // Color events without a project are drawn in
+}
+
+// ImportedCalendarProvider is the provider of calendars imported from
+// iCalendar files. They are never synced, their events stay as they were
+// imported.
+const ImportedCalendarProvider = "ics"
+
+// IsImported tells whether the calendar was imported from a file rather than
+// synced, so it can be removed.
+func (c CalendarSettings) IsImported() bool {
+	return c.Provider == ImportedCalendarProvider
+}
+
+// Calendar finds the settings of the calendar, nil when there are none.
+func (s *Settings) Calendar(id string) *CalendarSettings {
+	index := slices.IndexFunc(s.Calendars, func(calendar CalendarSettings) bool {
+		return calendar.ID == id
+	})
+
+	if index < 0 {
+		return nil
+	}
+
+	return &s.Calendars[index]
+}
+
+// ProviderCalendars are the calendars synced from the provider.
+func (s *Settings) ProviderCalendars(provider string) []CalendarSettings {
+	var calendars []CalendarSettings
+	for _, calendar := range s.Calendars {
+		if calendar.Provider == provider {
+			calendars = append(calendars, calendar)
+		}
+	}
+
+	return calendars
+}
+
+// MergeCalendars brings the calendar list up to date with the calendars the
+// account has, keeping the choices made for those it already knew and
+// dropping those which are gone.
+func (s *Settings) MergeCalendars(calendars []CalendarSettings) {
+	merged := make([]CalendarSettings, 0, len(calendars))
+
+	for _, calendar := range calendars {
+		if known := s.Calendar(calendar.ID); known != nil {
+			calendar.Visible = known.Visible
+			calendar.Color = known.Color
+		}
+
+		merged = append(merged, calendar)
+	}
+
+	s.Calendars = merged
+}
+
+// Marshal serializes the Settings to bytes using encoding/gob
+func (s *Settings) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	if err := encoder.Encode(s); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes bytes into the Settings using encoding/gob
+func (s *Settings) Unmarshal(data []byte) error {
+	buf := bytes.NewBuffer(data)
+	decoder := gob.NewDecoder(buf)
+	return decoder.Decode(s)
+}
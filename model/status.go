@@ -0,0 +1,109 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+//go:generate go tool go-enum -type=Status
+
+type Status int
+
+const (
+	Todo Status = iota
+	InProgress
+	Waiting
+	Done
+	Cancelled
+)
+
+// Label is the name of the status shown to users.
+func (s Status) Label() string {
+	switch s {
+	case Todo:
+		return "To do"
+	case InProgress:
+		return "In progress"
+	default:
+		return s.String()
+	}
+}
+
+// IsClosed reports whether work on a task with the status has ended.
+func (s Status) IsClosed() bool {
+	return s == Done || s == Cancelled
+}
+
+// StatusChange records when a task moved to a status.
+type StatusChange struct {
+	Status Status
+	At     time.Time
+}
+
+// Workflow lists the statuses a task may move to from each status.
+type Workflow map[Status][]Status
+
+// DefaultWorkflow lets tasks be started, parked and finished in any order and
+// reopened once they're closed.
+var DefaultWorkflow = Workflow{
+	Todo:       {InProgress, Waiting, Done, Cancelled},
+	InProgress: {Todo, Waiting, Done, Cancelled},
+	Waiting:    {Todo, InProgress, Done, Cancelled},
+	Done:       {Todo},
+	Cancelled:  {Todo},
+}
+
+// Allows reports whether the workflow permits moving from one status to another.
+func (w Workflow) Allows(from Status, to Status) bool {
+	return slices.Contains(w[from], to)
+}
+
+// ReadWorkflow decodes a workflow from JSON mapping each status name to the
+// names of the statuses tasks may move to from it.
+func ReadWorkflow(r io.Reader) (Workflow, error) {
+	var workflow Workflow
+	if err := json.NewDecoder(r).Decode(&workflow); err != nil {
+		return nil, fmt.Errorf("decoding workflow: %w", err)
+	}
+
+	if err := workflow.Validate(); err != nil {
+		return nil, err
+	}
+
+	return workflow, nil
+}
+
+// Validate checks that tasks can move on from every status, so none get stuck.
+func (w Workflow) Validate() error {
+	for _, status := range StatusValues() {
+		if len(w[status]) == 0 {
+			return fmt.Errorf("%s tasks have to be able to move on", status.Label())
+		}
+	}
+
+	return nil
+}
+
+// Next lists the statuses a task in the given status can move to.
+func (w Workflow) Next(from Status) []Status {
+	return w[from]
+}
+
+// TransitionError is returned when the workflow doesn't allow a status change.
+type TransitionError struct {
+	From Status
+	To   Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("a task can't move from %s to %s", e.From.Label(), e.To.Label())
+}
+
+func IsTransitionError(err error) bool {
+	var transitionError *TransitionError
+	return errors.As(err, &transitionError)
+}
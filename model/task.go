@@ -9,7 +9,9 @@ import (
 	"slices"
 	"time"
 
+	"github.com/guregu/null/v6"
 	"github.com/guregu/null/v6/zero"
+	"github.com/pleimann/camel-do/utils"
 )
 
 // Task represents a task in the task tracking application.
@@ -18,16 +20,26 @@ type Task struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 
-	Title       zero.String `form:"title"`                   // Title of the task
-	Description zero.String `form:"description"`             // Description of the task
-	StartTime   zero.Time   `form:"startTime"`               // Start time of the task
-	Duration    zero.Int32  `form:"duration"`                // Duration of the task
-	Completed   zero.Bool   `form:"completed,default:false"` // Status of task completion
-	Hidden      zero.Bool   `form:"hidden,default:false"`    // Status of task completion
-	Rank        zero.Int32  // Sort order
-	ProjectID   zero.String `form:"projectId"` // Foreign key referencing the project associated with the task.
-	GTaskID     zero.String
-	Position    TimelinePosition
+	Title           zero.String `form:"title"`       // Title of the task
+	Description     zero.String `form:"description"` // Description of the task
+	StartTime       zero.Time   `form:"startTime"`   // Start time of the task
+	Duration        zero.Int32  `form:"duration"`    // Duration of the task
+	Status          Status      // Where the task is in its workflow
+	AllDay          zero.Bool   `form:"allDay"`         // Task takes whole days rather than a time slot
+	ReminderOffset  null.Int32  `form:"reminderOffset"` // Minutes before the start to be reminded, 0 at the start
+	EndTime         zero.Time   // Exclusive end of a task spanning several days
+	Rank            zero.Int32  // Sort order
+	ProjectID       zero.String `form:"projectId"` // Foreign key referencing the project associated with the task.
+	ParentID        zero.String // Task this one is a subtask of
+	MilestoneID     zero.String `form:"milestoneId"` // Milestone of the project the task counts towards
+	GTaskID         zero.String
+	EventID         zero.String // Calendar event the task is published as
+	EventCalendarID zero.String // Calendar that event is on
+	SourceEventID   zero.String `form:"sourceEventId"` // Calendar event the task follows up on
+	Position        TimelinePosition
+
+	CustomValues  map[string]string // Values of the project's custom fields keyed by field ID
+	StatusHistory []StatusChange    // Every status the task moved to, oldest first
 }
 
 func NewTask(
@@ -38,8 +50,7 @@ func NewTask(
 	updatedAt time.Time,
 	startTime zero.Time,
 	duration zero.Int32,
-	completed zero.Bool,
-	hidden zero.Bool,
+	status Status,
 	rank zero.Int32,
 	projectID zero.String,
 	gTaskID zero.String,
@@ -52,21 +63,98 @@ func NewTask(
 		Description: description,
 		StartTime:   startTime,
 		Duration:    duration,
-		Completed:   completed,
-		Hidden:      hidden,
+		Status:      status,
 		Rank:        rank,
 		ProjectID:   projectID,
 		GTaskID:     gTaskID,
 	}
 
-	task.Position = NewTimelinePosition(
-		task.StartTime.Time,
-		duration.Int32,
-	)
+	if !task.InAllDayLane() {
+		task.Position = NewTimelinePosition(
+			task.StartTime.Time,
+			duration.Int32,
+		)
+	}
 
 	return task
 }
 
+// SetStatus moves the task to the status, recording when it did.
+func (t *Task) SetStatus(status Status, at time.Time) {
+	t.Status = status
+	t.StatusHistory = append(t.StatusHistory, StatusChange{Status: status, At: at})
+}
+
+// StatusSince is when the task moved to its current status, zero if it never
+// changed.
+func (t Task) StatusSince() time.Time {
+	if len(t.StatusHistory) == 0 {
+		return time.Time{}
+	}
+
+	return t.StatusHistory[len(t.StatusHistory)-1].At
+}
+
+// End is when the task finishes. All-day tasks without an explicit end finish
+// at the end of the day they start on.
+func (t Task) End() time.Time {
+	if t.EndTime.Valid {
+		return t.EndTime.Time
+	}
+
+	if t.AllDay.Bool {
+		year, month, day := t.StartTime.Time.Date()
+		return time.Date(year, month, day+1, 0, 0, 0, 0, t.StartTime.Time.Location())
+	}
+
+	return t.StartTime.Time.Add(time.Duration(t.Duration.Int32) * time.Minute)
+}
+
+// Overlaps reports whether the scheduled task covers any time in [start, end).
+func (t Task) Overlaps(start time.Time, end time.Time) bool {
+	if !t.StartTime.Valid {
+		return false
+	}
+
+	taskEnd := t.End()
+
+	// Tasks without a duration occupy just the instant they start at
+	if !taskEnd.After(t.StartTime.Time) {
+		return !t.StartTime.Time.Before(start) && t.StartTime.Time.Before(end)
+	}
+
+	return t.StartTime.Time.Before(end) && taskEnd.After(start)
+}
+
+// Days is the number of calendar days the scheduled task covers in the user's
+// time zone. An event set in another zone can cross midnight there but not
+// where it's shown.
+func (t Task) Days() int {
+	if !t.StartTime.Valid {
+		return 0
+	}
+
+	firstYear, firstMonth, firstDay := t.StartTime.Time.In(utils.Location()).Date()
+	first := time.Date(firstYear, firstMonth, firstDay, 0, 0, 0, 0, time.UTC)
+
+	last := t.End()
+	if last.After(t.StartTime.Time) {
+		// The end is exclusive so a task ending at midnight doesn't cover the next day
+		last = last.Add(-time.Nanosecond)
+	}
+
+	lastYear, lastMonth, lastDay := last.In(utils.Location()).Date()
+	lastDate := time.Date(lastYear, lastMonth, lastDay, 0, 0, 0, 0, time.UTC)
+
+	return int(lastDate.Sub(first).Hours()/24) + 1
+}
+
+// InAllDayLane reports whether the timeline shows the task in the all-day lane
+// above the time slots rather than in the grid.
+func (t Task) InAllDayLane() bool {
+	return t.AllDay.Bool || t.Days() > 1
+}
+
 func (t Task) MarshalJSONString() string {
 	json, err := t.MarshalJSON()
 	if err != nil {
@@ -77,19 +165,30 @@ func (t Task) MarshalJSONString() string {
 }
 
 func (t Task) MarshalJSON() ([]byte, error) {
-	return json.Marshal(map[string]any{
-		"id":          t.ID,
-		"title":       t.Title.String,
-		"description": t.Description.String,
-		"startTime":   t.StartTime.Time,
-		"duration":    t.Duration,
-		"completed":   t.Completed.Bool,
-		"hidden":      t.Hidden.Bool,
-		"rank":        t.Rank.Int32,
-		"projectId":   t.ProjectID.String,
-		"gTaskId":     t.GTaskID.String,
-		"position":    t.Position,
-	})
+	return json.Marshal(t.jsonFields())
+}
+
+func (t Task) jsonFields() map[string]any {
+	return map[string]any{
+		"id":             t.ID,
+		"title":          t.Title.String,
+		"description":    t.Description.String,
+		"startTime":      t.StartTime.Time,
+		"duration":       t.Duration,
+		"allDay":         t.AllDay.Bool,
+		"reminderOffset": t.ReminderOffset.Ptr(),
+		"endTime":        t.EndTime.Ptr(),
+		"status":         t.Status,
+		"statusHistory":  t.StatusHistory,
+		"rank":           t.Rank.Int32,
+		"projectId":      t.ProjectID.String,
+		"parentId":       t.ParentID.String,
+		"milestoneId":    t.MilestoneID.String,
+		"gTaskId":        t.GTaskID.String,
+		"sourceEventId":  t.SourceEventID.String,
+		"position":       t.Position,
+		"customValues":   t.CustomValues,
+	}
 }
 
 // Marshal serializes the Task to bytes using encoding/gob
@@ -130,6 +229,24 @@ func (tl *TaskList) Push(task Task) {
 	tl.tasks = append(tl.tasks, task)
 }
 
+// Filter returns a new list with the tasks keep returns true for.
+func (tl *TaskList) Filter(keep func(Task) bool) *TaskList {
+	filtered := NewTaskList()
+	for _, task := range tl.tasks {
+		if keep(task) {
+			filtered.Push(task)
+		}
+	}
+
+	return filtered
+}
+
+// SortFunc orders the list with a custom comparison, keeping equal tasks in
+// their current order.
+func (tl *TaskList) SortFunc(cmp func(a, b Task) int) {
+	slices.SortStableFunc(tl.tasks, cmp)
+}
+
 func (tl *TaskList) Sort() {
 	slices.SortFunc(tl.tasks, func(a, b Task) int {
 		if n := a.StartTime.Time.Compare(b.StartTime.Time); n != 0 {
@@ -174,7 +291,11 @@ func NewTimelinePosition(startTime time.Time, duration int32) TimelinePosition {
 	}
 }
 
+// slot is the timeline row the time starts in, by the clock of the user's zone
+// as stored times may be in another.
 func slot(t time.Time) int {
+	t = t.In(utils.Location())
+
 	return (t.Hour()-startHours)*int(60/slotMinutes) + (t.Minute() / slotMinutes) + 1
 }
 
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"github.com/guregu/null/v6"
+	"github.com/guregu/null/v6/zero"
+)
+
+// TimeOfDayFormat is the layout a project's preferred time of day is kept in.
+const TimeOfDayFormat = "15:04"
+
+// TaskDefaults are the values a project fills in on new tasks which leave them
+// empty.
+type TaskDefaults struct {
+	Duration       zero.Int32  `form:"defaultDuration"`       // Estimated minutes of work
+	Description    zero.String `form:"defaultDescription"`    // Notes which may contain {{date}} style variables
+	ReminderOffset null.Int32  `form:"defaultReminderOffset"` // Minutes before the start to be reminded, 0 at the start
+	TimeOfDay      zero.String `form:"defaultTimeOfDay"`      // Preferred start time, e.g. 09:30
+}
+
+// Apply fills in the task's empty fields from the defaults.
+func (d TaskDefaults) Apply(task *Task, now time.Time) {
+	if task.Duration.Int32 == 0 && d.Duration.Valid {
+		task.Duration = d.Duration
+	}
+
+	if task.Description.String == "" && d.Description.Valid {
+		task.Description = zero.StringFrom(ExpandVariables(d.Description.String, nil, now))
+	}
+
+	if !task.ReminderOffset.Valid && d.ReminderOffset.Valid {
+		task.ReminderOffset = d.ReminderOffset
+	}
+
+	// A start at midnight means only the day was picked
+	if task.StartTime.Valid && !task.AllDay.Bool {
+		start := task.StartTime.Time
+		if start.Hour() == 0 && start.Minute() == 0 {
+			if preferred, ok := d.On(start); ok {
+				task.StartTime = zero.TimeFrom(preferred)
+			}
+		}
+	}
+}
+
+// On is the preferred time of day on the given date, if the project has one.
+func (d TaskDefaults) On(date time.Time) (time.Time, bool) {
+	if !d.TimeOfDay.Valid {
+		return time.Time{}, false
+	}
+
+	timeOfDay, err := time.Parse(TimeOfDayFormat, d.TimeOfDay.String)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	year, month, day := date.Date()
+
+	return time.Date(year, month, day, timeOfDay.Hour(), timeOfDay.Minute(), 0, 0, date.Location()), true
+}
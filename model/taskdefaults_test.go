@@ -0,0 +1,98 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/guregu/null/v6"
+	"github.com/guregu/null/v6/zero"
+)
+
+func TestTaskDefaultsApply(t *testing.T) {
+	now := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+
+	defaults := TaskDefaults{
+		Duration:       zero.Int32From(45),
+		ReminderOffset: null.Int32From(15),
+		TimeOfDay:      zero.StringFrom("09:30"),
+	}
+
+	tests := []struct {
+		name         string
+		task         Task
+		wantDuration int32
+		wantReminder null.Int32
+		wantStart    time.Time
+	}{
+		{
+			name:         "empty task takes every default",
+			task:         Task{},
+			wantDuration: 45,
+			wantReminder: null.Int32From(15),
+		},
+		{
+			name:         "reminder at the start is kept",
+			task:         Task{Duration: zero.Int32From(30), ReminderOffset: null.Int32From(0)},
+			wantDuration: 30,
+			wantReminder: null.Int32From(0),
+		},
+		{
+			name:         "day without a time starts at the preferred time",
+			task:         Task{StartTime: zero.TimeFrom(time.Date(2025, 6, 3, 0, 0, 0, 0, time.UTC))},
+			wantDuration: 45,
+			wantReminder: null.Int32From(15),
+			wantStart:    time.Date(2025, 6, 3, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:         "picked time is kept",
+			task:         Task{StartTime: zero.TimeFrom(time.Date(2025, 6, 3, 14, 0, 0, 0, time.UTC))},
+			wantDuration: 45,
+			wantReminder: null.Int32From(15),
+			wantStart:    time.Date(2025, 6, 3, 14, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := tt.task
+			defaults.Apply(&task, now)
+
+			if task.Duration.Int32 != tt.wantDuration {
+				t.Errorf("duration = %d, want %d", task.Duration.Int32, tt.wantDuration)
+			}
+
+			if task.ReminderOffset != tt.wantReminder {
+				t.Errorf("reminder offset = %v, want %v", task.ReminderOffset, tt.wantReminder)
+			}
+
+			if !task.StartTime.Time.Equal(tt.wantStart) {
+				t.Errorf("start = %s, want %s", task.StartTime.Time, tt.wantStart)
+			}
+		})
+	}
+}
+
+func TestTaskDefaultsOn(t *testing.T) {
+	date := time.Date(2025, 6, 3, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		timeOfDay zero.String
+		want      time.Time
+		wantOk    bool
+	}{
+		{name: "preferred time", timeOfDay: zero.StringFrom("09:30"), want: time.Date(2025, 6, 3, 9, 30, 0, 0, time.UTC), wantOk: true},
+		{name: "no preference", timeOfDay: zero.String{}},
+		{name: "unreadable preference", timeOfDay: zero.StringFrom("half nine")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := TaskDefaults{TimeOfDay: tt.timeOfDay}.On(date)
+
+			if ok != tt.wantOk || !got.Equal(tt.want) {
+				t.Errorf("On() = %s, %v, want %s, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
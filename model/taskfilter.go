@@ -0,0 +1,96 @@
+package model
+
+import (
+	"strings"
+)
+
+// OpenStatuses is the TaskFilter status matching every task which isn't done
+// or cancelled.
+const OpenStatuses = "open"
+
+// TaskFilter narrows down and orders a TaskList, e.g. for the backlog.
+type TaskFilter struct {
+	ProjectID  string `query:"projectId"`
+	Status     string `query:"status"` // Status name, OpenStatuses or empty for any
+	FieldID    string `query:"field"`  // Custom field to filter on
+	FieldValue string `query:"value"`  // Value the custom field should match
+	SortBy     string `query:"sort"`   // Custom field to sort by
+	Descending bool   `query:"desc"`
+}
+
+// IsEmpty reports whether the filter neither filters nor sorts.
+func (f TaskFilter) IsEmpty() bool {
+	return f.ProjectID == "" && f.Status == "" && f.FieldID == "" && f.SortBy == ""
+}
+
+// Apply returns the tasks matching the filter in the requested order. Custom
+// fields are looked up in projects so their values compare by type.
+func (f TaskFilter) Apply(tasks *TaskList, projects *ProjectIndex) *TaskList {
+	filtered := tasks.Filter(func(task Task) bool {
+		// A project's tasks include those of its sub-projects
+		if f.ProjectID != "" && task.ProjectID.String != f.ProjectID &&
+			!projects.IsDescendant(task.ProjectID.String, f.ProjectID) {
+			return false
+		}
+
+		// Tasks of archived projects are only listed when asked for by project
+		if f.ProjectID == "" && projects.IsArchived(task.ProjectID.String) {
+			return false
+		}
+
+		if !f.matchesStatus(task.Status) {
+			return false
+		}
+
+		if f.FieldID != "" && f.FieldValue != "" {
+			field, ok := projects.CustomField(f.FieldID)
+			if !ok {
+				return false
+			}
+
+			return matchesFieldValue(field, task.CustomValues[f.FieldID], f.FieldValue)
+		}
+
+		return true
+	})
+
+	if f.SortBy != "" {
+		if field, ok := projects.CustomField(f.SortBy); ok {
+			filtered.SortFunc(func(a, b Task) int {
+				va, vb := a.CustomValues[f.SortBy], b.CustomValues[f.SortBy]
+
+				// Keep tasks without a value at the end in either direction
+				if f.Descending && va != "" && vb != "" {
+					return field.Compare(vb, va)
+				}
+
+				return field.Compare(va, vb)
+			})
+		}
+	}
+
+	return filtered
+}
+
+func (f TaskFilter) matchesStatus(status Status) bool {
+	switch f.Status {
+	case "":
+		return true
+
+	case OpenStatuses:
+		return !status.IsClosed()
+
+	default:
+		return status.String() == f.Status
+	}
+}
+
+func matchesFieldValue(field CustomField, value string, want string) bool {
+	switch field.Type {
+	case TextField, URLField:
+		return strings.Contains(strings.ToLower(value), strings.ToLower(want))
+
+	default:
+		return field.Compare(value, want) == 0
+	}
+}
@@ -0,0 +1,185 @@
+package model
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"iter"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/guregu/null/v6/zero"
+)
+
+// templateVariable matches placeholders such as {{date}} or {{ client name }}
+var templateVariable = regexp.MustCompile(`{{\s*([^{}]+?)\s*}}`)
+
+// builtinVariables are filled in automatically when a template is instantiated
+var builtinVariables = map[string]func(now time.Time) string{
+	"date": func(now time.Time) string {
+		return now.Format("2006-01-02")
+	},
+	"time": func(now time.Time) string {
+		return now.Format("15:04")
+	},
+	"weekday": func(now time.Time) string {
+		return now.Weekday().String()
+	},
+	"week": func(now time.Time) string {
+		_, week := now.ISOWeek()
+		return fmt.Sprintf("%d", week)
+	},
+	"month": func(now time.Time) string {
+		return now.Format("January")
+	},
+	"year": func(now time.Time) string {
+		return now.Format("2006")
+	},
+}
+
+// ExpandVariables replaces {{name}} placeholders in text with the built-in
+// date values or the given values. Unknown placeholders are left as they are.
+func ExpandVariables(text string, values map[string]string, now time.Time) string {
+	return templateVariable.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := templateVariable.FindStringSubmatch(placeholder)[1]
+
+		if value, ok := values[name]; ok {
+			return value
+		}
+
+		if builtin, ok := builtinVariables[strings.ToLower(name)]; ok {
+			return builtin(now)
+		}
+
+		return placeholder
+	})
+}
+
+// TaskTemplate is a blueprint for a task, and optionally its subtasks, which is
+// created over and over again.
+type TaskTemplate struct {
+	ID        string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Name         string      `form:"name"`         // Name of the template in the template manager
+	TitlePattern string      `form:"titlePattern"` // Title of the created task which may contain {{variables}}
+	Description  zero.String `form:"description"`  // Description of the created task which may contain {{variables}}
+	Duration     zero.Int32  `form:"duration"`     // Duration of the created task
+	ProjectID    zero.String `form:"projectId"`    // Project the created task belongs to
+	Subtasks     []string    // Titles of the subtasks created along with the task
+}
+
+// Variables lists the placeholders used by the template which have to be
+// prompted for, i.e. all but the built-in date values.
+func (t *TaskTemplate) Variables() []string {
+	var variables []string
+
+	texts := append([]string{t.TitlePattern, t.Description.String}, t.Subtasks...)
+	for _, text := range texts {
+		for _, match := range templateVariable.FindAllStringSubmatch(text, -1) {
+			name := match[1]
+
+			if _, builtin := builtinVariables[strings.ToLower(name)]; builtin || slices.Contains(variables, name) {
+				continue
+			}
+
+			variables = append(variables, name)
+		}
+	}
+
+	return variables
+}
+
+// Instantiate creates the task and its subtasks described by the template. The
+// subtasks still need the parent's ID once it has been stored.
+func (t *TaskTemplate) Instantiate(values map[string]string, now time.Time) (Task, []Task) {
+	task := Task{
+		CreatedAt: now,
+		UpdatedAt: now,
+		Title:     zero.StringFrom(ExpandVariables(t.TitlePattern, values, now)),
+		Duration:  t.Duration,
+		ProjectID: t.ProjectID,
+	}
+
+	if t.Description.Valid {
+		task.Description = zero.StringFrom(ExpandVariables(t.Description.String, values, now))
+	}
+
+	subtasks := make([]Task, 0, len(t.Subtasks))
+	for i, title := range t.Subtasks {
+		subtasks = append(subtasks, Task{
+			CreatedAt: now,
+			UpdatedAt: now,
+			Title:     zero.StringFrom(ExpandVariables(title, values, now)),
+			Rank:      zero.Int32From(int32(i)),
+			ProjectID: t.ProjectID,
+		})
+	}
+
+	return task, subtasks
+}
+
+// NewTaskTemplateFromTask creates a template which recreates the task along
+// with its subtasks.
+func NewTaskTemplateFromTask(task Task, subtasks *TaskList) TaskTemplate {
+	taskTemplate := TaskTemplate{
+		Name:         task.Title.String,
+		TitlePattern: task.Title.String,
+		Description:  task.Description,
+		Duration:     task.Duration,
+		ProjectID:    task.ProjectID,
+	}
+
+	for subtask := range subtasks.All() {
+		taskTemplate.Subtasks = append(taskTemplate.Subtasks, subtask.Title.String)
+	}
+
+	return taskTemplate
+}
+
+// Marshal serializes the TaskTemplate to bytes using encoding/gob
+func (t *TaskTemplate) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	err := encoder.Encode(t)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes bytes into the TaskTemplate using encoding/gob
+func (t *TaskTemplate) Unmarshal(data []byte) error {
+	buf := bytes.NewBuffer(data)
+	decoder := gob.NewDecoder(buf)
+	return decoder.Decode(t)
+}
+
+type TaskTemplateIndex struct {
+	templates map[string]TaskTemplate
+}
+
+func NewTaskTemplateIndex() *TaskTemplateIndex {
+	return &TaskTemplateIndex{
+		templates: make(map[string]TaskTemplate),
+	}
+}
+
+func (ti *TaskTemplateIndex) Len() int {
+	return len(ti.templates)
+}
+
+// Values iterates the templates ordered by name.
+func (ti *TaskTemplateIndex) Values() iter.Seq[TaskTemplate] {
+	return slices.Values(slices.SortedFunc(maps.Values(ti.templates), func(a, b TaskTemplate) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	}))
+}
+
+func (ti *TaskTemplateIndex) Add(taskTemplate TaskTemplate) {
+	ti.templates[taskTemplate.ID] = taskTemplate
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// WorkingHours are when tasks are fit in when they're scheduled without a
+// time being picked.
+type WorkingHours struct {
+	Start string         `form:"workStart"` // Time of day work starts, e.g. 09:00
+	End   string         `form:"workEnd"`   // Time of day work ends, e.g. 17:30
+	Days  []time.Weekday `form:"workDays"`  // Days of the week worked
+}
+
+// DefaultWorkingHours are those used until others are set.
+var DefaultWorkingHours = WorkingHours{
+	Start: "09:00",
+	End:   "17:00",
+	Days:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+}
+
+// OrDefault are the working hours, or the default ones when none were set.
+func (w WorkingHours) OrDefault() WorkingHours {
+	if w.Start == "" || w.End == "" {
+		return DefaultWorkingHours
+	}
+
+	return w
+}
+
+// Validate checks that work ends after it starts on at least one day.
+func (w WorkingHours) Validate() error {
+	start, err := time.Parse(TimeOfDayFormat, w.Start)
+	if err != nil {
+		return errors.New("the start of work isn't a time of day")
+	}
+
+	end, err := time.Parse(TimeOfDayFormat, w.End)
+	if err != nil {
+		return errors.New("the end of work isn't a time of day")
+	}
+
+	if !end.After(start) {
+		return errors.New("work has to end after it starts")
+	}
+
+	if len(w.Days) == 0 {
+		return errors.New("pick at least one day of work")
+	}
+
+	return nil
+}
+
+// On is when work starts and ends on the day of date, in date's location.
+// Days off aren't worked at all.
+func (w WorkingHours) On(date time.Time) (time.Time, time.Time, bool) {
+	if !slices.Contains(w.Days, date.Weekday()) {
+		return time.Time{}, time.Time{}, false
+	}
+
+	start, err := time.Parse(TimeOfDayFormat, w.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	end, err := time.Parse(TimeOfDayFormat, w.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	year, month, day := date.Date()
+
+	return time.Date(year, month, day, start.Hour(), start.Minute(), 0, 0, date.Location()),
+		time.Date(year, month, day, end.Hour(), end.Minute(), 0, 0, date.Location()),
+		true
+}
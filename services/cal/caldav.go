@@ -0,0 +1,320 @@
+package cal
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pleimann/camel-do/model"
+)
+
+const calDAVProviderName = "caldav"
+
+// calDAVHorizon is how far ahead events are synced from CalDAV servers.
+const calDAVHorizon = 365 * 24 * time.Hour
+
+// calDAVClient talks to CalDAV servers, giving up on those which don't answer
+// rather than holding up the sync.
+var calDAVClient = &http.Client{Timeout: 30 * time.Second}
+
+// CalDAVProvider syncs the calendars of an account on a CalDAV (RFC 4791)
+// server such as Nextcloud, Fastmail or Radicale. It fetches the events of the
+// sync window every time rather than only what changed.
+type CalDAVProvider struct {
+	client   *http.Client
+	url      *url.URL
+	username string
+	password string
+}
+
+func NewCalDAVProvider(account model.CalDAVAccount, client *http.Client) (*CalDAVProvider, error) {
+	accountURL, err := url.Parse(account.URL)
+	if err != nil || !accountURL.IsAbs() {
+		return nil, fmt.Errorf("CalDAV needs the full URL of the account, not %q", account.URL)
+	}
+
+	return &CalDAVProvider{
+		client:   client,
+		url:      accountURL,
+		username: account.Username,
+		password: account.Password,
+	}, nil
+}
+
+func (p *CalDAVProvider) Name() string {
+	return calDAVProviderName
+}
+
+// ListCalendars lists the event calendars in the account's calendar home. They
+// all start out visible, in the preset closest to their color on the server.
+func (p *CalDAVProvider) ListCalendars(ctx context.Context) ([]model.CalendarSettings, error) {
+	home, err := p.calendarHome(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses, err := p.propfind(ctx, home, "1", `<d:resourcetype/><d:displayname/><ical:calendar-color/><c:supported-calendar-component-set/>`)
+	if err != nil {
+		return nil, fmt.Errorf("listing calendars: %w", err)
+	}
+
+	var calendars []model.CalendarSettings
+	for _, response := range responses {
+		prop := response.prop()
+		if prop.ResourceType.Calendar == nil || !prop.supports("VEVENT") {
+			continue
+		}
+
+		calendarURL, err := home.Parse(response.Href)
+		if err != nil {
+			return nil, fmt.Errorf("listing calendars: %w", err)
+		}
+
+		name := prop.DisplayName
+		if name == "" {
+			name = strings.Trim(calendarURL.Path[strings.LastIndex(strings.TrimSuffix(calendarURL.Path, "/"), "/")+1:], "/")
+		}
+
+		color := model.Sky
+		if hexColor, err := model.ParseHexColor(trimAlpha(prop.CalendarColor)); err == nil {
+			color = model.NearestColor(hexColor)
+		}
+
+		calendars = append(calendars, model.CalendarSettings{
+			ID:       calendarURL.Path,
+			Provider: calDAVProviderName,
+			Name:     name,
+			Visible:  true,
+			Color:    color,
+		})
+	}
+
+	return calendars, nil
+}
+
+// ListChanges lists every event of the calendar in the sync window, with the
+// server expanding recurring events into their instances.
+func (p *CalDAVProvider) ListChanges(ctx context.Context, calendarID string, syncToken string) (*EventChanges, error) {
+	calendarURL, err := p.url.Parse(calendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	start := now.Add(-syncHistory).UTC().Format("20060102T150405Z")
+	end := now.Add(calDAVHorizon).UTC().Format("20060102T150405Z")
+
+	query := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop>
+    <c:calendar-data><c:expand start="%[1]s" end="%[2]s"/></c:calendar-data>
+  </d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT"><c:time-range start="%[1]s" end="%[2]s"/></c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`, start, end)
+
+	responses, err := p.request(ctx, "REPORT", calendarURL, "1", query)
+	if err != nil {
+		return nil, fmt.Errorf("querying events: %w", err)
+	}
+
+	changes := &EventChanges{Full: true}
+
+	for _, response := range responses {
+		calendarData := response.prop().CalendarData
+		if calendarData == "" {
+			continue
+		}
+
+		root, err := parseICalendar(strings.NewReader(calendarData))
+		if err != nil {
+			slog.Warn("skipping unreadable CalDAV event", "href", response.Href, "error", err)
+			continue
+		}
+
+		for _, vevent := range root.Find("VEVENT") {
+			if vevent.Text("STATUS") == "CANCELLED" {
+				continue
+			}
+
+			event, err := icalEvent(vevent)
+			if err != nil {
+				slog.Warn("skipping unreadable CalDAV event", "href", response.Href, "error", err)
+				continue
+			}
+
+			event.Declined = icalDeclinedBy(vevent, p.username)
+			event.Attendees = icalAttendees(vevent, p.username)
+
+			changes.Events = append(changes.Events, event)
+		}
+	}
+
+	return changes, nil
+}
+
+// calendarHome finds the collection the account's calendars are in by way of
+// the principal of the signed in user. Servers which don't say are taken to
+// have been given the calendar home itself.
+func (p *CalDAVProvider) calendarHome(ctx context.Context) (*url.URL, error) {
+	principal := p.url
+
+	responses, err := p.propfind(ctx, p.url, "0", `<d:current-user-principal/>`)
+	if err != nil {
+		return nil, fmt.Errorf("finding the CalDAV principal: %w", err)
+	}
+
+	if href := firstHref(responses, func(prop davProp) *davHref { return prop.CurrentUserPrincipal }); href != "" {
+		if principal, err = p.url.Parse(href); err != nil {
+			return nil, err
+		}
+	}
+
+	responses, err = p.propfind(ctx, principal, "0", `<c:calendar-home-set/>`)
+	if err != nil {
+		return nil, fmt.Errorf("finding the CalDAV calendar home: %w", err)
+	}
+
+	if href := firstHref(responses, func(prop davProp) *davHref { return prop.CalendarHomeSet }); href != "" {
+		return principal.Parse(href)
+	}
+
+	return p.url, nil
+}
+
+func (p *CalDAVProvider) propfind(ctx context.Context, target *url.URL, depth string, props string) ([]davResponse, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:ical="http://apple.com/ns/ical/">
+  <d:prop>` + props + `</d:prop>
+</d:propfind>`
+
+	return p.request(ctx, "PROPFIND", target, depth, body)
+}
+
+// request sends a WebDAV request and reads the multi-status response.
+func (p *CalDAVProvider) request(ctx context.Context, method string, target *url.URL, depth string, body string) ([]davResponse, error) {
+	request, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewBufferString(body))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	request.Header.Set("Depth", depth)
+
+	if p.username != "" || p.password != "" {
+		request.SetBasicAuth(p.username, p.password)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusMultiStatus {
+		io.Copy(io.Discard, response.Body)
+
+		return nil, fmt.Errorf("%s %s: %s", method, target.Path, response.Status)
+	}
+
+	multistatus := davMultistatus{}
+	if err := xml.NewDecoder(response.Body).Decode(&multistatus); err != nil {
+		return nil, fmt.Errorf("%s %s: reading response: %w", method, target.Path, err)
+	}
+
+	return multistatus.Responses, nil
+}
+
+type davMultistatus struct {
+	Responses []davResponse `xml:"DAV: response"`
+}
+
+type davResponse struct {
+	Href      string        `xml:"DAV: href"`
+	Propstats []davPropstat `xml:"DAV: propstat"`
+}
+
+// prop is the properties the server found, leaving out those it reports as
+// missing.
+func (r davResponse) prop() davProp {
+	for _, propstat := range r.Propstats {
+		if strings.Contains(propstat.Status, " 200 ") {
+			return propstat.Prop
+		}
+	}
+
+	return davProp{}
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"DAV: prop"`
+	Status string  `xml:"DAV: status"`
+}
+
+type davProp struct {
+	CurrentUserPrincipal *davHref `xml:"DAV: current-user-principal"`
+	CalendarHomeSet      *davHref `xml:"urn:ietf:params:xml:ns:caldav calendar-home-set"`
+	ResourceType         struct {
+		Calendar *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+	} `xml:"DAV: resourcetype"`
+	DisplayName   string `xml:"DAV: displayname"`
+	CalendarColor string `xml:"http://apple.com/ns/ical/ calendar-color"`
+	SupportedSet  *struct {
+		Components []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"urn:ietf:params:xml:ns:caldav comp"`
+	} `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set"`
+	CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+}
+
+// supports tells whether the calendar holds the component, like VEVENT rather
+// than only VTODO. Calendars which don't say hold any.
+func (p davProp) supports(component string) bool {
+	if p.SupportedSet == nil || len(p.SupportedSet.Components) == 0 {
+		return true
+	}
+
+	for _, supported := range p.SupportedSet.Components {
+		if strings.EqualFold(supported.Name, component) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type davHref struct {
+	Href string `xml:"DAV: href"`
+}
+
+func firstHref(responses []davResponse, href func(prop davProp) *davHref) string {
+	for _, response := range responses {
+		if found := href(response.prop()); found != nil && found.Href != "" {
+			return strings.TrimSpace(found.Href)
+		}
+	}
+
+	return ""
+}
+
+// trimAlpha drops the alpha channel Apple's calendar-color carries, as in
+// #1E90FFFF.
+func trimAlpha(color string) string {
+	color = strings.TrimSpace(color)
+	if len(color) == len("#rrggbbaa") {
+		return color[:len("#rrggbb")]
+	}
+
+	return color
+}
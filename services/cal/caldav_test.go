@@ -0,0 +1,258 @@
+package cal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pleimann/camel-do/model"
+)
+
+// calDAVStandIn answers the requests CalDAVProvider makes the way a server
+// like Radicale does, for an account with one event calendar and one task list.
+func calDAVStandIn(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	multistatus := func(w http.ResponseWriter, responses string) {
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>
+<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:ical="http://apple.com/ns/ical/">%s</d:multistatus>`, responses)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "ana" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+
+		switch {
+		case r.Method == "PROPFIND" && strings.Contains(string(body), "current-user-principal"):
+			multistatus(w, `<d:response><d:href>`+r.URL.Path+`</d:href><d:propstat>
+				<d:prop><d:current-user-principal><d:href>/principals/ana/</d:href></d:current-user-principal></d:prop>
+				<d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+
+		case r.Method == "PROPFIND" && r.URL.Path == "/principals/ana/":
+			multistatus(w, `<d:response><d:href>/principals/ana/</d:href><d:propstat>
+				<d:prop><c:calendar-home-set><d:href>/calendars/ana/</d:href></c:calendar-home-set></d:prop>
+				<d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+
+		case r.Method == "PROPFIND" && r.URL.Path == "/calendars/ana/" && r.Header.Get("Depth") == "1":
+			multistatus(w, `
+				<d:response><d:href>/calendars/ana/</d:href><d:propstat>
+					<d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop>
+					<d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>
+				<d:response><d:href>/calendars/ana/work/</d:href>
+					<d:propstat><d:prop>
+						<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>
+						<d:displayname>Work</d:displayname>
+						<ical:calendar-color>#FB7185FF</ical:calendar-color>
+						<c:supported-calendar-component-set><c:comp name="VEVENT"/></c:supported-calendar-component-set>
+					</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>
+				</d:response>
+				<d:response><d:href>/calendars/ana/chores/</d:href>
+					<d:propstat><d:prop>
+						<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>
+						<d:displayname>Chores</d:displayname>
+						<c:supported-calendar-component-set><c:comp name="VTODO"/></c:supported-calendar-component-set>
+					</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>
+				</d:response>`)
+
+		case r.Method == "REPORT" && r.URL.Path == "/calendars/ana/work/":
+			if !strings.Contains(string(body), `<c:comp-filter name="VEVENT">`) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			multistatus(w, `
+				<d:response><d:href>/calendars/ana/work/standup.ics</d:href><d:propstat><d:prop><c:calendar-data>BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:standup
+DTSTART;TZID=America/New_York:20250310T093000
+DTEND;TZID=America/New_York:20250310T094500
+SUMMARY:Standup\, daily
+DESCRIPTION:Dial in:\n
+  call link
+RECURRENCE-ID;TZID=America/New_York:20250310T093000
+END:VEVENT
+BEGIN:VEVENT
+UID:standup
+DTSTART;TZID=America/New_York:20250311T093000
+DURATION:PT15M
+SUMMARY:Standup\, daily
+RECURRENCE-ID;TZID=America/New_York:20250311T093000
+END:VEVENT
+END:VCALENDAR
+</c:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>
+				<d:response><d:href>/calendars/ana/work/offsite.ics</d:href><d:propstat><d:prop><c:calendar-data>BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:offsite
+DTSTART;VALUE=DATE:20250312
+DTEND;VALUE=DATE:20250314
+SUMMARY:Team offsite
+END:VEVENT
+END:VCALENDAR
+</c:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>
+				<d:response><d:href>/calendars/ana/work/cancelled.ics</d:href><d:propstat><d:prop><c:calendar-data>BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:cancelled
+DTSTART:20250313T150000Z
+DTEND:20250313T160000Z
+SUMMARY:Called off
+STATUS:CANCELLED
+END:VEVENT
+END:VCALENDAR
+</c:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestCalDAVProviderListCalendars(t *testing.T) {
+	server := calDAVStandIn(t)
+	defer server.Close()
+
+	provider, err := NewCalDAVProvider(model.CalDAVAccount{URL: server.URL + "/", Username: "ana", Password: "secret"}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calendars, err := provider.ListCalendars(context.Background())
+	if err != nil {
+		t.Fatalf("ListCalendars() error = %v", err)
+	}
+
+	if len(calendars) != 1 {
+		t.Fatalf("ListCalendars() = %d calendars, want only the event calendar: %+v", len(calendars), calendars)
+	}
+
+	want := model.CalendarSettings{
+		ID:       "/calendars/ana/work/",
+		Provider: calDAVProviderName,
+		Name:     "Work",
+		Visible:  true,
+		Color:    model.NearestColor("#fb7185"),
+	}
+
+	if calendars[0] != want {
+		t.Errorf("ListCalendars()[0] = %+v, want %+v", calendars[0], want)
+	}
+}
+
+func TestCalDAVProviderWrongPassword(t *testing.T) {
+	server := calDAVStandIn(t)
+	defer server.Close()
+
+	provider, err := NewCalDAVProvider(model.CalDAVAccount{URL: server.URL, Username: "ana", Password: "wrong"}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := provider.ListCalendars(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("ListCalendars() error = %v, want 401 Unauthorized", err)
+	}
+}
+
+func TestCalDAVProviderListChanges(t *testing.T) {
+	server := calDAVStandIn(t)
+	defer server.Close()
+
+	provider, err := NewCalDAVProvider(model.CalDAVAccount{URL: server.URL, Username: "ana", Password: "secret"}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := provider.ListChanges(context.Background(), "/calendars/ana/work/", "")
+	if err != nil {
+		t.Fatalf("ListChanges() error = %v", err)
+	}
+
+	if !changes.Full {
+		t.Error("ListChanges().Full = false, want CalDAV to always list every event")
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data isn't available", err)
+	}
+
+	tests := []struct {
+		id       string
+		title    string
+		start    time.Time
+		duration int32
+		allDay   bool
+		endTime  time.Time
+		hasNotes bool
+	}{
+		{
+			id:       "standup/20250310T093000",
+			title:    "Standup, daily",
+			start:    time.Date(2025, 3, 10, 9, 30, 0, 0, newYork),
+			duration: 15,
+			hasNotes: true,
+		},
+		{
+			id:       "standup/20250311T093000",
+			title:    "Standup, daily",
+			start:    time.Date(2025, 3, 11, 9, 30, 0, 0, newYork),
+			duration: 15,
+		},
+		{
+			id:       "offsite",
+			title:    "Team offsite",
+			start:    time.Date(2025, 3, 12, 0, 0, 0, 0, time.Local),
+			duration: 2 * 24 * 60,
+			allDay:   true,
+			endTime:  time.Date(2025, 3, 14, 0, 0, 0, 0, time.Local),
+		},
+	}
+
+	if len(changes.Events) != len(tests) {
+		t.Fatalf("ListChanges() = %d events, want %d without the cancelled one", len(changes.Events), len(tests))
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			event := changes.Events[i]
+
+			if event.ID != tt.id {
+				t.Errorf("ID = %q, want %q", event.ID, tt.id)
+			}
+
+			if event.Title.String != tt.title {
+				t.Errorf("Title = %q, want %q", event.Title.String, tt.title)
+			}
+
+			if !event.StartTime.Time.Equal(tt.start) {
+				t.Errorf("StartTime = %s, want %s", event.StartTime.Time, tt.start)
+			}
+
+			if event.Duration.Int32 != tt.duration {
+				t.Errorf("Duration = %d, want %d", event.Duration.Int32, tt.duration)
+			}
+
+			if event.AllDay.Bool != tt.allDay {
+				t.Errorf("AllDay = %t, want %t", event.AllDay.Bool, tt.allDay)
+			}
+
+			if tt.allDay && !event.EndTime.Time.Equal(tt.endTime) {
+				t.Errorf("EndTime = %s, want %s", event.EndTime.Time, tt.endTime)
+			}
+
+			if tt.hasNotes && event.Description.String != "Dial in:\n call link" {
+				t.Errorf("Description = %q, want the unescaped notes", event.Description.String)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package cal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/angelofallars/htmx-go"
+	"github.com/labstack/echo/v4"
+
+	"github.com/pleimann/camel-do/model"
+	"github.com/pleimann/camel-do/templates/components"
+	"github.com/pleimann/camel-do/templates/pages"
+	"github.com/pleimann/camel-do/utils"
+)
+
+type CalendarHandler struct {
+	*echo.Group
+	calendarService *CalendarService
+	taskService     TaskService
+	projectService  ProjectService
+}
+
+// TaskService interface to avoid circular dependencies
+type TaskService interface {
+	GetAllTasks() (*model.TaskList, error)
+	GetProjectTasks(projectID string) (*model.TaskList, error)
+	GetTasksScheduledBetween(start time.Time, end time.Time) (*model.TaskList, error)
+}
+
+// ProjectService interface to avoid circular dependencies
+type ProjectService interface {
+	GetProject(id string) (*model.Project, error)
+	GetAllProjects() (*model.ProjectIndex, error)
+}
+
+func NewCalendarHandler(group *echo.Group, calendarService *CalendarService, taskService TaskService, projectService ProjectService) *CalendarHandler {
+	calendarHandler := &CalendarHandler{
+		Group:           group,
+		calendarService: calendarService,
+		taskService:     taskService,
+		projectService:  projectService,
+	}
+
+	group.GET("/sync", calendarHandler.handleSyncStatus).Name = "calendar-sync-status"
+	group.POST("/sync", calendarHandler.handleSync).Name = "sync-calendar"
+	group.PUT("/caldav", calendarHandler.handleCalDAVAccount).Name = "caldav-account"
+	group.POST("/import", calendarHandler.handleImport).Name = "import-calendar"
+	group.DELETE("/imported/:id", calendarHandler.handleRemoveImported).Name = "remove-imported-calendar"
+	group.GET("/export.ics", calendarHandler.handleExport).Name = "export-tasks"
+
+	return calendarHandler
+}
+
+// handleSyncStatus shows when the calendar last synced. Given the sync time
+// the page last saw, it has the timeline reload if a sync happened since.
+func (h *CalendarHandler) handleSyncStatus(c echo.Context) error {
+	status, err := h.calendarService.SyncStatus()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting calendar sync status", err)
+	}
+
+	response := htmx.NewResponse()
+
+	if since := c.QueryParam("since"); since != "" && since != strconv.FormatInt(status.LastSynced.Unix(), 10) {
+		response = response.AddTrigger(htmx.Trigger("calendar-synced"))
+	}
+
+	return h.renderSyncStatus(c, response, status)
+}
+
+// handleSync syncs the calendar now rather than waiting for the background
+// sync, then has the timeline reload its events.
+func (h *CalendarHandler) handleSync(c echo.Context) error {
+	response := htmx.NewResponse()
+
+	// A failed sync shows in the status rather than as an error
+	if err := h.calendarService.Sync(c.Request().Context()); err == nil {
+		response = response.AddTrigger(htmx.Trigger("calendar-synced"))
+	}
+
+	status, err := h.calendarService.SyncStatus()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting calendar sync status", err)
+	}
+
+	return h.renderSyncStatus(c, response, status)
+}
+
+func (h *CalendarHandler) renderSyncStatus(c echo.Context, response htmx.Response, status model.CalendarSync) error {
+	if err := response.RenderTempl(c.Request().Context(), c.Response().Writer, components.CalendarSyncStatus(status, time.Now())); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handleCalDAVAccount sets up the CalDAV account, then syncs in the background
+// so its calendars show up in the settings and on the timeline.
+func (h *CalendarHandler) handleCalDAVAccount(c echo.Context) error {
+	account := model.CalDAVAccount{}
+	if err := c.Bind(&account); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "reading CalDAV account", err)
+	}
+
+	account.URL = strings.TrimSpace(account.URL)
+
+	message := "Saved, its calendars show up once they've synced"
+	if !account.IsConfigured() {
+		message = "Removed"
+	}
+
+	if err := h.calendarService.SetCalDAVAccount(c.Request().Context(), account); err != nil {
+		return h.renderCalDAVAccount(c, account.Redacted(), false, "", err.Error())
+	}
+
+	go func() {
+		if err := h.calendarService.Sync(context.Background()); err != nil {
+			slog.Warn("syncing calendar after changing the CalDAV account", "error", err)
+		}
+	}()
+
+	return h.renderCalDAVAccount(c, account.Redacted(), account.IsConfigured(), message, "")
+}
+
+func (h *CalendarHandler) renderCalDAVAccount(c echo.Context, account model.CalDAVAccount, passwordSaved bool, message string, failure string) error {
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, pages.CalDAVAccountForm(account, passwordSaved, message, failure)); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handleImport adds the events of an uploaded iCalendar file as a calendar of
+// their own, listing it in the settings and showing its events on the
+// timeline.
+func (h *CalendarHandler) handleImport(c echo.Context) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "reading calendar file", err)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "reading calendar file", err)
+	}
+
+	defer file.Close()
+
+	name, events, err := ReadICS(file, time.Now().Add(ImportHorizon))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("%s isn't a calendar file: %s", fileHeader.Filename, err))
+	}
+
+	if len(events) == 0 {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("%s has no events", fileHeader.Filename))
+	}
+
+	if name == "" {
+		name = strings.TrimSuffix(fileHeader.Filename, filepath.Ext(fileHeader.Filename))
+	}
+
+	calendar, err := h.calendarService.ImportCalendar(name, events)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "importing calendar", err)
+	}
+
+	if err := htmx.NewResponse().
+		AddTrigger(htmx.Trigger("calendar-changed")).
+		RenderTempl(c.Request().Context(), c.Response().Writer, pages.CalendarSettingsItem(*calendar)); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handleRemoveImported removes a calendar imported from a file along with its
+// events.
+func (h *CalendarHandler) handleRemoveImported(c echo.Context) error {
+	calendarID, err := url.PathUnescape(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "reading calendar ID", err)
+	}
+
+	if err := h.calendarService.RemoveImportedCalendar(calendarID); err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "removing calendar", err)
+		}
+
+		return echo.NewHTTPError(http.StatusInternalServerError, "removing calendar", err)
+	}
+
+	return htmx.NewResponse().AddTrigger(htmx.Trigger("calendar-changed")).Write(c.Response().Writer)
+}
+
+// handleExport downloads scheduled tasks as an iCalendar file, either those of
+// a project or those in a range of days, or those of a project in the range.
+func (h *CalendarHandler) handleExport(c echo.Context) error {
+	projectID := c.QueryParam("projectId")
+
+	var start, end time.Time
+	if c.QueryParam("start") != "" || c.QueryParam("end") != "" {
+		var err error
+		if start, err = utils.ParseDate(time.DateOnly, c.QueryParam("start")); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "reading start date", err)
+		}
+
+		if end, err = utils.ParseDate(time.DateOnly, c.QueryParam("end")); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "reading end date", err)
+		}
+
+		// The range takes in the whole of its last day
+		end = end.AddDate(0, 0, 1)
+
+		if !end.After(start) {
+			return echo.NewHTTPError(http.StatusBadRequest, "the end date is before the start date")
+		}
+	}
+
+	name := "Camel Do"
+
+	var tasks *model.TaskList
+	var err error
+
+	switch {
+	case projectID != "":
+		project, err := h.projectService.GetProject(projectID)
+		if err != nil {
+			if utils.IsNotFoundError(err) {
+				return echo.NewHTTPError(http.StatusNotFound, "getting project", err)
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting project", err)
+		}
+
+		name = project.Name
+
+		tasks, err = h.taskService.GetProjectTasks(projectID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting project tasks", err)
+		}
+
+		if !start.IsZero() {
+			tasks = tasks.Filter(func(task model.Task) bool {
+				return task.Overlaps(start, end)
+			})
+		}
+
+	case !start.IsZero():
+		tasks, err = h.taskService.GetTasksScheduledBetween(start, end)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting scheduled tasks", err)
+		}
+
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "pick a project or a range of days to export")
+	}
+
+	tasks.Sort()
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name+".ics"))
+
+	return WriteICS(c.Response(), name, tasks.All(), time.Now())
+}
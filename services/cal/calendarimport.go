@@ -0,0 +1,128 @@
+package cal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/pleimann/camel-do/model"
+	"github.com/pleimann/camel-do/utils"
+)
+
+// ImportHorizon is how far ahead recurring events of imported files are
+// expanded into instances.
+const ImportHorizon = 2 * 365 * 24 * time.Hour
+
+// ImportCalendar adds the events read from an iCalendar file as a calendar of
+// their own, shown on the timeline alongside the synced ones until it's
+// removed.
+func (s *CalendarService) ImportCalendar(name string, events []model.Event) (*model.CalendarSettings, error) {
+	slog.Debug("CalendarService.ImportCalendar", "name", name, "events", len(events))
+
+	// A sync in progress would drop a calendar it didn't know about
+	s.syncMutex.Lock()
+	defer s.syncMutex.Unlock()
+
+	calendar := model.CalendarSettings{
+		ID:       model.ImportedCalendarProvider + "-" + ulid.Make().String(),
+		Provider: model.ImportedCalendarProvider,
+		Name:     name,
+		Visible:  true,
+		Color:    model.Violet,
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		calendars, err := tx.CreateBucketIfNotExists([]byte(eventsBucket))
+		if err != nil {
+			return err
+		}
+
+		bucket, err := calendars.CreateBucket([]byte(calendar.ID))
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			eventBytes, err := event.Marshal()
+			if err != nil {
+				return err
+			}
+
+			if err := bucket.Put([]byte(event.ID), eventBytes); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("importing calendar %s: %w", name, err)
+	}
+
+	err = s.settings.UpdateSettings(func(settings *model.Settings) error {
+		settings.Calendars = append(settings.Calendars, calendar)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("importing calendar %s: %w", name, err)
+	}
+
+	return &calendar, nil
+}
+
+// RemoveImportedCalendar removes a calendar imported from a file along with
+// its events. Synced calendars can only be hidden.
+func (s *CalendarService) RemoveImportedCalendar(id string) error {
+	slog.Debug("CalendarService.RemoveImportedCalendar", "id", id)
+
+	s.syncMutex.Lock()
+	defer s.syncMutex.Unlock()
+
+	settings, err := s.settings.GetSettings()
+	if err != nil {
+		return fmt.Errorf("removing calendar %s: %w", id, err)
+	}
+
+	if calendar := settings.Calendar(id); calendar == nil || !calendar.IsImported() {
+		return utils.NewNotFoundError("imported calendar", id)
+	}
+
+	err = s.settings.UpdateSettings(func(settings *model.Settings) error {
+		settings.Calendars = slices.DeleteFunc(settings.Calendars, func(calendar model.CalendarSettings) bool {
+			return calendar.ID == id
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("removing calendar %s: %w", id, err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(eventsBucket))
+		if bucket == nil {
+			return nil
+		}
+
+		if err := bucket.DeleteBucket([]byte(id)); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("removing calendar %s: %w", id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package cal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/pleimann/camel-do/model"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// taskIDProperty is the private extended property published events carry the
+// ID of their task in, so they can be told apart from other events.
+const taskIDProperty = "camelDoTaskId"
+
+// PublishTask mirrors the task as an event on the calendar chosen in the
+// settings, adding, updating or removing the event it is linked to so it
+// matches the task. It returns the calendar and ID of the event the task is
+// linked to afterwards, both empty when there is none.
+func (s *CalendarService) PublishTask(ctx context.Context, task *model.Task) (string, string, error) {
+	slog.Debug("CalendarService.PublishTask", "taskId", task.ID)
+
+	settings, err := s.settings.GetSettings()
+	if err != nil {
+		return task.EventCalendarID.String, task.EventID.String, fmt.Errorf("publishing task %s: %w", task.ID, err)
+	}
+
+	calendarID, eventID := task.EventCalendarID.String, task.EventID.String
+	publishTo := settings.Publishing.CalendarID
+
+	// Tasks which are off the timeline or cancelled, and every task once
+	// publishing is turned off, have no event
+	if !task.StartTime.Valid || task.Status == model.Cancelled || !settings.Publishing.IsEnabled() {
+		if eventID != "" {
+			if err := s.deleteEvent(ctx, calendarID, eventID); err != nil {
+				return calendarID, eventID, fmt.Errorf("unpublishing task %s: %w", task.ID, err)
+			}
+		}
+
+		return "", "", nil
+	}
+
+	// Publishing moved to another calendar since the event was added
+	if eventID != "" && calendarID != publishTo {
+		if err := s.deleteEvent(ctx, calendarID, eventID); err != nil {
+			return calendarID, eventID, fmt.Errorf("moving published task %s: %w", task.ID, err)
+		}
+
+		eventID = ""
+	}
+
+	event := taskEvent(task, settings.Publishing.Busy)
+
+	if eventID != "" {
+		updated, err := s.google.service.Events.Update(publishTo, eventID, event).Context(ctx).Do()
+		if err == nil {
+			return publishTo, updated.Id, nil
+		}
+
+		// Someone deleted the event on the calendar, so it's added again
+		if !isMissing(err) {
+			return calendarID, eventID, fmt.Errorf("updating published task %s: %w", task.ID, err)
+		}
+	}
+
+	inserted, err := s.google.service.Events.Insert(publishTo, event).Context(ctx).Do()
+	if err != nil {
+		return "", "", fmt.Errorf("publishing task %s: %w", task.ID, err)
+	}
+
+	return publishTo, inserted.Id, nil
+}
+
+// UnpublishTask removes the event the task is published as, e.g. because the
+// task is being deleted.
+func (s *CalendarService) UnpublishTask(ctx context.Context, task *model.Task) error {
+	slog.Debug("CalendarService.UnpublishTask", "taskId", task.ID)
+
+	if !task.EventID.Valid {
+		return nil
+	}
+
+	if err := s.deleteEvent(ctx, task.EventCalendarID.String, task.EventID.String); err != nil {
+		return fmt.Errorf("unpublishing task %s: %w", task.ID, err)
+	}
+
+	return nil
+}
+
+// deleteEvent deletes the event unless it's already gone.
+func (s *CalendarService) deleteEvent(ctx context.Context, calendarID string, eventID string) error {
+	if err := s.google.service.Events.Delete(calendarID, eventID).Context(ctx).Do(); err != nil && !isMissing(err) {
+		return err
+	}
+
+	return nil
+}
+
+// isMissing tells whether the Calendar API failed because the event, or the
+// calendar it was on, no longer exists.
+func isMissing(err error) bool {
+	var apiErr *googleapi.Error
+
+	return errors.As(err, &apiErr) && (apiErr.Code == http.StatusNotFound || apiErr.Code == http.StatusGone)
+}
+
+// taskSummary is the title of the event a task is published or exported as,
+// ticked off when the task is done.
+func taskSummary(task *model.Task) string {
+	if task.Status == model.Done {
+		return "✓ " + task.Title.String
+	}
+
+	return task.Title.String
+}
+
+// taskEvent is the event a task is published as. Done tasks are ticked off
+// and no longer keep the time busy.
+func taskEvent(task *model.Task, busy bool) *calendar.Event {
+	event := &calendar.Event{
+		Summary:      taskSummary(task),
+		Description:  task.Description.String,
+		Transparency: "transparent",
+		ExtendedProperties: &calendar.EventExtendedProperties{
+			Private: map[string]string{taskIDProperty: task.ID},
+		},
+		Reminders: &calendar.EventReminders{UseDefault: true},
+	}
+
+	if task.Status != model.Done && busy {
+		event.Transparency = "opaque"
+	}
+
+	if task.AllDay.Bool {
+		end := task.EndTime.Time
+		if !task.EndTime.Valid {
+			end = task.StartTime.Time.AddDate(0, 0, 1)
+		}
+
+		// All day events end on the day after their last
+		event.Start = &calendar.EventDateTime{Date: task.StartTime.Time.Format(time.DateOnly)}
+		event.End = &calendar.EventDateTime{Date: end.Format(time.DateOnly)}
+
+	} else {
+		end := task.StartTime.Time.Add(time.Duration(task.Duration.Int32) * time.Minute)
+
+		event.Start = &calendar.EventDateTime{DateTime: task.StartTime.Time.Format(time.RFC3339)}
+		event.End = &calendar.EventDateTime{DateTime: end.Format(time.RFC3339)}
+	}
+
+	if task.ReminderOffset.Valid {
+		event.Reminders = &calendar.EventReminders{
+			Overrides: []*calendar.EventReminder{
+				{Method: "popup", Minutes: int64(task.ReminderOffset.Int32)},
+			},
+			ForceSendFields: []string{"UseDefault"},
+		}
+	}
+
+	return event
+}
@@ -2,109 +2,269 @@ package cal
 
 import (
 	"context"
+	"encoding/gob"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	bolt "go.etcd.io/bbolt"
 
-	"github.com/guregu/null/v6/zero"
 	"github.com/pleimann/camel-do/model"
 	"github.com/pleimann/camel-do/services/oauth"
-	"google.golang.org/api/calendar/v3"
-	"google.golang.org/api/option"
+	"github.com/pleimann/camel-do/utils"
 )
 
 type CalendarServiceConfig struct {
 }
 
-// TaskService is a service for managing tasks.
-type CalendarService struct {
-	config         *CalendarServiceConfig
-	db             *bolt.DB
-	googleCalendar *calendar.Service
+// SettingsService interface to avoid circular dependencies
+type SettingsService interface {
+	GetSettings() (*model.Settings, error)
+	UpdateSettings(update func(settings *model.Settings) error) error
 }
 
-func NewCalendarService(config *CalendarServiceConfig, googleAuth *oauth.GoogleAuth, db *bolt.DB) (*CalendarService, error) {
-	client := googleAuth.GetClient()
-
-	ctx := context.Background()
+// CalendarService is a service for the calendar events shown alongside tasks,
+// synced from Google Calendar and any CalDAV account set up.
+type CalendarService struct {
+	config   *CalendarServiceConfig
+	db       *bolt.DB
+	google   *GoogleProvider
+	settings SettingsService
 
-	service, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	syncMutex sync.Mutex // Keeps syncs, and imports, from overlapping
+	syncError error      // Why the last sync failed, guarded by syncMutex
+}
 
+func NewCalendarService(config *CalendarServiceConfig, googleAuth *oauth.GoogleAuth, db *bolt.DB, settings SettingsService) (*CalendarService, error) {
+	google, err := NewGoogleProvider(googleAuth)
 	if err != nil {
-		return nil, fmt.Errorf("error creating google calendar service: %w", err)
+		return nil, err
 	}
 
 	calendarService := &CalendarService{
-		config:         config,
-		db:             db,
-		googleCalendar: service,
+		config:   config,
+		db:       db,
+		google:   google,
+		settings: settings,
 	}
 
+	gob.Register(model.Event{})
+
 	return calendarService, nil
 }
 
+// SetCalDAVAccount sets up the CalDAV account to sync calendars from, after
+// checking its calendars can be listed. An account without a URL removes it.
+// A blank password keeps the one saved.
+func (s *CalendarService) SetCalDAVAccount(ctx context.Context, account model.CalDAVAccount) error {
+	slog.Debug("CalendarService.SetCalDAVAccount", "url", account.URL, "username", account.Username)
+
+	settings, err := s.settings.GetSettings()
+	if err != nil {
+		return err
+	}
+
+	if account.Password == "" && account.URL == settings.CalDAV.URL && account.Username == settings.CalDAV.Username {
+		account.Password = settings.CalDAV.Password
+	}
+
+	if account.IsConfigured() {
+		provider, err := NewCalDAVProvider(account, calDAVClient)
+		if err != nil {
+			return err
+		}
+
+		if _, err := provider.ListCalendars(ctx); err != nil {
+			return fmt.Errorf("connecting to %s: %w", account.URL, err)
+		}
+	}
+
+	return s.settings.UpdateSettings(func(settings *model.Settings) error {
+		settings.CalDAV = account
+
+		return nil
+	})
+}
+
+// providers are the calendar services to sync from: Google, along with the
+// CalDAV account when one is set up.
+func (s *CalendarService) providers(settings *model.Settings) ([]CalendarProvider, error) {
+	providers := []CalendarProvider{s.google}
+
+	if settings.CalDAV.IsConfigured() {
+		calDAV, err := NewCalDAVProvider(settings.CalDAV, calDAVClient)
+		if err != nil {
+			return nil, err
+		}
+
+		providers = append(providers, calDAV)
+	}
+
+	return providers, nil
+}
+
 func (t *CalendarService) GetTodaysEvents() (*model.EventList, error) {
-	year, month, day := time.Now().Date()
-	start := time.Date(year, month, day, 0, 0, 0, 0, time.Now().Location())
+	slog.Debug("CalendarService.GetTodaysEvents")
 
-	slog.Debug("CalendarService.GetTodaysEvents", "start", start)
+	return t.GetEventsOnDate(utils.Now())
+}
 
-	eventList, err := t.getUpcomingEvents(start, time.Hour*24)
+// GetEvent finds a cached event by its ID on whichever calendar it's on,
+// colored the way its calendar is.
+func (s *CalendarService) GetEvent(id string) (*model.Event, error) {
+	slog.Debug("CalendarService.GetEvent", "id", id)
 
+	settings, err := s.settings.GetSettings()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("fetching event %s: %w", id, err)
 	}
 
-	return eventList, nil
+	var event *model.Event
+	err = s.db.View(func(tx *bolt.Tx) error {
+		for _, calendarSettings := range settings.Calendars {
+			bucket := calendarEventsBucket(tx, calendarSettings.ID)
+
+			if bucket == nil {
+				continue
+			}
+
+			if eventBytes := bucket.Get([]byte(id)); eventBytes != nil {
+				event = &model.Event{}
+
+				if err := event.Unmarshal(eventBytes); err != nil {
+					return err
+				}
+
+				event.CalendarID = calendarSettings.ID
+				event.Color = calendarSettings.Color
+
+				return nil
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("fetching event %s: %w", id, err)
+	}
+
+	if event == nil {
+		return nil, utils.NewNotFoundError("event", id)
+	}
+
+	return event, nil
 }
 
-func (s *CalendarService) getUpcomingEvents(
-	startTime time.Time,
-	duration time.Duration,
-) (*model.EventList, error) {
-	slog.Debug("CalendarService.getUpcomingEvents", "startTime", startTime, "duration", duration)
-
-	events, err := s.googleCalendar.Events.
-		List("primary").
-		ShowDeleted(false).
-		SingleEvents(true).
-		TimeMin(startTime.Local().Format(time.RFC3339)).
-		TimeMax(startTime.Add(duration).Format(time.RFC3339)).
-		MaxResults(10).
-		OrderBy("startTime").
-		Do()
+// GetEventsOnDate returns the events taking place on the day of date in the
+// user's time zone.
+func (s *CalendarService) GetEventsOnDate(date time.Time) (*model.EventList, error) {
+	slog.Debug("CalendarService.GetEventsOnDate", "date", date)
+
+	beginningOfDay := utils.StartOfDay(date)
+
+	endOfDay := beginningOfDay.AddDate(0, 0, 1)
+
+	return s.GetEventsBetween(beginningOfDay, endOfDay)
+}
 
+// GetEventsBetween returns the cached events of the visible calendars taking
+// place in [start, end), colored the way their calendar is.
+func (s *CalendarService) GetEventsBetween(start time.Time, end time.Time) (*model.EventList, error) {
+	slog.Debug("CalendarService.GetEventsBetween", "start", start, "end", end)
+
+	settings, err := s.settings.GetSettings()
 	if err != nil {
-		return nil, fmt.Errorf("error getting events: %w", err)
+		return nil, fmt.Errorf("fetching events between %s and %s: %w", start, end, err)
 	}
 
 	eventList := model.NewEventList()
-	for _, event := range events.Items {
-		eventList.Push(toModelEvent(event))
+	err = s.db.View(func(tx *bolt.Tx) error {
+		for _, calendarSettings := range settings.Calendars {
+			if !calendarSettings.Visible {
+				continue
+			}
+
+			bucket := calendarEventsBucket(tx, calendarSettings.ID)
+
+			if bucket == nil {
+				continue
+			}
+
+			err := bucket.ForEach(func(k, eventBytes []byte) error {
+				event := model.Event{}
+
+				if err := event.Unmarshal(eventBytes); err != nil {
+					return err
+				}
+
+				// Published tasks are on the timeline already as themselves
+				if event.TaskID != "" {
+					return nil
+				}
+
+				// Events spanning several days show on each of them
+				if event.Overlaps(start, end) {
+					event.CalendarID = calendarSettings.ID
+					event.Color = calendarSettings.Color
+
+					eventList.Push(event)
+				}
+
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("fetching events between %s and %s: %w", start, end, err)
 	}
 
+	eventList.Sort()
+
 	return eventList, nil
 }
 
-func toModelEvent(event *calendar.Event) model.Event {
-	startTime, _ := time.Parse(time.RFC3339, event.Start.DateTime)
-	endTime, _ := time.Parse(time.RFC3339, event.End.DateTime)
-	createdTime, _ := time.Parse(time.RFC3339, event.Created)
-	updatedTime, _ := time.Parse(time.RFC3339, event.Updated)
-
-	duration := endTime.Sub(startTime)
-
-	return model.Event{
-		Task: model.Task{
-			CreatedAt:   createdTime,
-			UpdatedAt:   updatedTime,
-			Title:       zero.StringFrom(event.Summary),
-			Description: zero.StringFrom(event.Description),
-			StartTime:   zero.TimeFrom(startTime),
-			Duration:    zero.Int32From(int32(duration.Minutes())),
-			GTaskID:     zero.StringFrom(event.Id),
-		},
+// GetEventsByDay returns the events of each of the days from the day of start
+// in the user's time zone on, reading the cache once for them all rather than
+// once a day. Events spanning several days are on each of them.
+func (s *CalendarService) GetEventsByDay(start time.Time, days int) ([]*model.EventList, error) {
+	slog.Debug("CalendarService.GetEventsByDay", "start", start, "days", days)
+
+	first := utils.StartOfDay(start)
+
+	events, err := s.GetEventsBetween(first, first.AddDate(0, 0, days))
+	if err != nil {
+		return nil, err
+	}
+
+	return eventsByDay(events, first, days), nil
+}
+
+// eventsByDay sorts the events into the days from first on which they take
+// place, keeping their order.
+func eventsByDay(events *model.EventList, first time.Time, days int) []*model.EventList {
+	byDay := make([]*model.EventList, days)
+	for i := range byDay {
+		byDay[i] = model.NewEventList()
+	}
+
+	for event := range events.All() {
+		for i := range byDay {
+			// Days are found by date rather than by adding hours so those
+			// a daylight saving change shortens or lengthens line up
+			if event.Overlaps(first.AddDate(0, 0, i), first.AddDate(0, 0, i+1)) {
+				byDay[i].Push(event)
+			}
+		}
 	}
+
+	return byDay
 }
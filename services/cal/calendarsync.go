@@ -0,0 +1,296 @@
+package cal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/pleimann/camel-do/model"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Events are kept in a bucket per calendar, nested in the events bucket and
+// keyed by event ID. Each calendar's sync token is kept under its ID.
+const (
+	eventsBucket       = "events"
+	calendarSyncBucket = "calendarSync"
+	syncTokensBucket   = "syncTokens"
+
+	lastSyncedKey = "lastSynced"
+)
+
+func calendarEventsBucket(tx *bolt.Tx, calendarID string) *bolt.Bucket {
+	bucket := tx.Bucket([]byte(eventsBucket))
+
+	if bucket == nil {
+		return nil
+	}
+
+	return bucket.Bucket([]byte(calendarID))
+}
+
+// syncHistory is how far back syncing goes. Later syncs only fetch what
+// changed when the provider can tell, so the window then stays anchored where
+// it started.
+const syncHistory = 4 * 7 * 24 * time.Hour
+
+// RunSync keeps the event cache up to date, syncing right away and then every
+// interval until the context is done.
+func (s *CalendarService) RunSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sync(ctx); err != nil {
+			slog.Warn("syncing calendar", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync brings the event cache up to date with the calendar providers. Once
+// there is a sync token only the events changed since the previous sync are
+// fetched.
+func (s *CalendarService) Sync(ctx context.Context) error {
+	s.syncMutex.Lock()
+	defer s.syncMutex.Unlock()
+
+	s.syncError = s.sync(ctx)
+
+	return s.syncError
+}
+
+// SyncStatus tells when the cache was last brought up to date.
+func (s *CalendarService) SyncStatus() (model.CalendarSync, error) {
+	status := model.CalendarSync{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(calendarSyncBucket))
+
+		if bucket == nil {
+			return nil
+		}
+
+		if lastSynced := bucket.Get([]byte(lastSyncedKey)); lastSynced != nil {
+			return status.LastSynced.UnmarshalText(lastSynced)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return status, fmt.Errorf("fetching calendar sync status: %w", err)
+	}
+
+	s.syncMutex.Lock()
+	defer s.syncMutex.Unlock()
+
+	if s.syncError != nil {
+		status.Error = s.syncError.Error()
+	}
+
+	return status, nil
+}
+
+// sync refreshes the list of calendars of each provider and then their events.
+func (s *CalendarService) sync(ctx context.Context) error {
+	slog.Debug("CalendarService.sync")
+
+	settings, err := s.settings.GetSettings()
+	if err != nil {
+		return err
+	}
+
+	providers, err := s.providers(settings)
+	if err != nil {
+		return err
+	}
+
+	// One calendar failing to sync shouldn't hold up the others
+	var calendars []model.CalendarSettings
+	var syncErrors []error
+
+	for _, provider := range providers {
+		listed, err := provider.ListCalendars(ctx)
+		if err != nil {
+			syncErrors = append(syncErrors, fmt.Errorf("listing %s calendars: %w", provider.Name(), err))
+
+			// Its events stay cached until the provider can be reached again
+			calendars = append(calendars, settings.ProviderCalendars(provider.Name())...)
+			continue
+		}
+
+		calendars = append(calendars, listed...)
+
+		for _, calendar := range listed {
+			if err := s.syncCalendar(ctx, provider, calendar.ID); err != nil {
+				syncErrors = append(syncErrors, fmt.Errorf("syncing calendar %s: %w", calendar.Name, err))
+			}
+		}
+	}
+
+	// Imported calendars aren't synced but stay until they're removed
+	calendars = append(calendars, settings.ProviderCalendars(model.ImportedCalendarProvider)...)
+
+	err = s.settings.UpdateSettings(func(settings *model.Settings) error {
+		settings.MergeCalendars(calendars)
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		if err := forgetRemovedCalendars(tx, calendars); err != nil {
+			return err
+		}
+
+		if len(syncErrors) > 0 {
+			return nil
+		}
+
+		syncBucket, err := tx.CreateBucketIfNotExists([]byte(calendarSyncBucket))
+		if err != nil {
+			return err
+		}
+
+		lastSynced, err := time.Now().MarshalText()
+		if err != nil {
+			return err
+		}
+
+		return syncBucket.Put([]byte(lastSyncedKey), lastSynced)
+	})
+
+	return errors.Join(append(syncErrors, err)...)
+}
+
+// syncCalendar brings the cached events of a calendar up to date.
+func (s *CalendarService) syncCalendar(ctx context.Context, provider CalendarProvider, calendarID string) error {
+	syncToken, err := s.syncToken(calendarID)
+	if err != nil {
+		return err
+	}
+
+	changes, err := provider.ListChanges(ctx, calendarID, syncToken)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		events, err := tx.CreateBucketIfNotExists([]byte(eventsBucket))
+		if err != nil {
+			return err
+		}
+
+		if changes.Full {
+			if err := events.DeleteBucket([]byte(calendarID)); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+				return err
+			}
+		}
+
+		bucket, err := events.CreateBucketIfNotExists([]byte(calendarID))
+		if err != nil {
+			return err
+		}
+
+		for _, id := range changes.Cancelled {
+			if err := bucket.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+
+		for _, event := range changes.Events {
+			eventBytes, err := event.Marshal()
+			if err != nil {
+				return err
+			}
+
+			if err := bucket.Put([]byte(event.ID), eventBytes); err != nil {
+				return err
+			}
+		}
+
+		syncTokens, err := tx.CreateBucketIfNotExists([]byte(syncTokensBucket))
+		if err != nil {
+			return err
+		}
+
+		return syncTokens.Put([]byte(calendarID), []byte(changes.NextSyncToken))
+	})
+}
+
+// forgetRemovedCalendars drops the events and sync tokens of calendars which
+// are no longer on the account.
+func forgetRemovedCalendars(tx *bolt.Tx, calendars []model.CalendarSettings) error {
+	isKnown := func(id []byte) bool {
+		return slices.ContainsFunc(calendars, func(calendar model.CalendarSettings) bool {
+			return calendar.ID == string(id)
+		})
+	}
+
+	for _, bucketName := range []string{eventsBucket, syncTokensBucket} {
+		bucket := tx.Bucket([]byte(bucketName))
+
+		if bucket == nil {
+			continue
+		}
+
+		// Collect first as the bucket can't change while it's walked
+		var removed [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			if !isKnown(k) {
+				removed = append(removed, k)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for _, id := range removed {
+			if bucket.Bucket(id) != nil {
+				err = bucket.DeleteBucket(id)
+			} else {
+				err = bucket.Delete(id)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *CalendarService) syncToken(calendarID string) (string, error) {
+	var syncToken string
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket([]byte(syncTokensBucket)); bucket != nil {
+			syncToken = string(bucket.Get([]byte(calendarID)))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("fetching calendar sync token: %w", err)
+	}
+
+	return syncToken, nil
+}
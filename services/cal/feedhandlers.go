@@ -0,0 +1,91 @@
+package cal
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/pleimann/camel-do/model"
+)
+
+// feedHistory is how far back feeds go. Tasks done with long ago are left out
+// to keep the feeds small, later ones are always in.
+const feedHistory = 90 * 24 * time.Hour
+
+// FeedHandler serves the feeds calendar apps subscribe to for the scheduled
+// tasks. Calendar apps can't sign in, so the token in the link is what keeps
+// a feed private.
+type FeedHandler struct {
+	*echo.Group
+	settingsService SettingsService
+	taskService     TaskService
+	projectService  ProjectService
+}
+
+func NewFeedHandler(group *echo.Group, settingsService SettingsService, taskService TaskService, projectService ProjectService) *FeedHandler {
+	feedHandler := &FeedHandler{
+		Group:           group,
+		settingsService: settingsService,
+		taskService:     taskService,
+		projectService:  projectService,
+	}
+
+	group.GET("/:token/tasks.ics", feedHandler.handleTasksFeed).Name = "tasks-feed"
+
+	return feedHandler
+}
+
+// handleTasksFeed renders the scheduled tasks of the feed the token is for as
+// an iCalendar file.
+func (h *FeedHandler) handleTasksFeed(c echo.Context) error {
+	settings, err := h.settingsService.GetSettings()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting settings", err)
+	}
+
+	// Revoked and made up tokens are told apart from neither
+	feed := settings.FeedByToken(c.Param("token"))
+	if feed == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "no such feed")
+	}
+
+	since := time.Now().Add(-feedHistory)
+	keep := func(task model.Task) bool {
+		return task.StartTime.Valid && task.End().After(since)
+	}
+
+	name := "Camel Do"
+
+	if feed.ProjectID != "" {
+		projects, err := h.projectService.GetAllProjects()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+		}
+
+		if projects.Get(feed.ProjectID) == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "the feed's project was deleted")
+		}
+
+		name = projects.Path(feed.ProjectID)
+		projectIDs := append(projects.Descendants(feed.ProjectID), feed.ProjectID)
+
+		inTime := keep
+		keep = func(task model.Task) bool {
+			return inTime(task) && slices.Contains(projectIDs, task.ProjectID.String)
+		}
+	}
+
+	tasks, err := h.taskService.GetAllTasks()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting tasks", err)
+	}
+
+	tasks = tasks.Filter(keep)
+	tasks.Sort()
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/calendar; charset=utf-8")
+
+	return WriteICS(c.Response(), name, tasks.All(), time.Now())
+}
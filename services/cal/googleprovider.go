@@ -0,0 +1,237 @@
+package cal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/guregu/null/v6/zero"
+	"github.com/pleimann/camel-do/model"
+	"github.com/pleimann/camel-do/services/oauth"
+	"github.com/pleimann/camel-do/utils"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+const googleProviderName = "google"
+
+// Google lists 250 events a page unless asked for more, up to 2500. Bigger
+// pages take fewer round trips for busy calendars without making any one of
+// them slow.
+const (
+	googleEventsPageSize   = 1000
+	googleCalendarPageSize = 250
+)
+
+// GoogleProvider syncs the calendars of the Google account signed in with.
+type GoogleProvider struct {
+	service *calendar.Service
+}
+
+func NewGoogleProvider(googleAuth *oauth.GoogleAuth) (*GoogleProvider, error) {
+	client := googleAuth.GetClient()
+
+	service, err := calendar.NewService(context.Background(), option.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("error creating google calendar service: %w", err)
+	}
+
+	return &GoogleProvider{service: service}, nil
+}
+
+func (p *GoogleProvider) Name() string {
+	return googleProviderName
+}
+
+// ListCalendars lists the calendars of the account. The primary calendar and
+// those shown in Google Calendar start out visible, in the preset closest to
+// their color there.
+func (p *GoogleProvider) ListCalendars(ctx context.Context) ([]model.CalendarSettings, error) {
+	var calendars []model.CalendarSettings
+
+	err := p.service.CalendarList.List().MaxResults(googleCalendarPageSize).Pages(ctx, func(list *calendar.CalendarList) error {
+		for _, entry := range list.Items {
+			name := entry.SummaryOverride
+			if name == "" {
+				name = entry.Summary
+			}
+
+			color := model.Sky
+			if hexColor, err := model.ParseHexColor(entry.BackgroundColor); err == nil {
+				color = model.NearestColor(hexColor)
+			}
+
+			calendars = append(calendars, model.CalendarSettings{
+				ID:       entry.Id,
+				Provider: googleProviderName,
+				Name:     name,
+				Primary:  entry.Primary,
+				Writable: entry.AccessRole == "owner" || entry.AccessRole == "writer",
+				Visible:  entry.Primary || entry.Selected,
+				Color:    color,
+			})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return calendars, nil
+}
+
+// ListChanges pages through the events changed since the sync token was
+// handed out, or through every event in the sync window without one.
+func (p *GoogleProvider) ListChanges(ctx context.Context, calendarID string, syncToken string) (*EventChanges, error) {
+	changes, err := p.listChanges(ctx, calendarID, syncToken)
+
+	// Google expires sync tokens now and then, which calls for a full sync
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusGone {
+		slog.Info("calendar sync token expired, syncing everything", "calendarId", calendarID, "error", err)
+
+		return p.listChanges(ctx, calendarID, "")
+	}
+
+	return changes, err
+}
+
+func (p *GoogleProvider) listChanges(ctx context.Context, calendarID string, syncToken string) (*EventChanges, error) {
+	call := p.service.Events.
+		List(calendarID).
+		SingleEvents(true).
+		MaxResults(googleEventsPageSize).
+		Context(ctx)
+
+	if syncToken != "" {
+		call = call.SyncToken(syncToken)
+
+	} else {
+		call = call.TimeMin(time.Now().Add(-syncHistory).Format(time.RFC3339))
+	}
+
+	changes := &EventChanges{Full: syncToken == ""}
+
+	// The sync token for next time comes with the last page
+	err := call.Pages(ctx, func(events *calendar.Events) error {
+		for _, event := range events.Items {
+			if event.Status == "cancelled" {
+				changes.Cancelled = append(changes.Cancelled, event.Id)
+
+			} else {
+				changes.Events = append(changes.Events, toModelEvent(event))
+			}
+		}
+
+		changes.NextSyncToken = events.NextSyncToken
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func toModelEvent(event *calendar.Event) model.Event {
+	createdTime, _ := time.Parse(time.RFC3339, event.Created)
+	updatedTime, _ := time.Parse(time.RFC3339, event.Updated)
+
+	startTime, endTime, allDay := googleEventTimes(event)
+
+	duration := endTime.Sub(startTime)
+
+	var taskID string
+	if event.ExtendedProperties != nil {
+		taskID = event.ExtendedProperties.Private[taskIDProperty]
+	}
+
+	modelEvent := model.Event{
+		Task: model.Task{
+			CreatedAt:   createdTime,
+			UpdatedAt:   updatedTime,
+			Title:       zero.StringFrom(event.Summary),
+			Description: zero.StringFrom(event.Description),
+			StartTime:   zero.TimeFrom(startTime),
+			Duration:    zero.Int32From(int32(duration.Minutes())),
+			ID:          event.Id,
+			GTaskID:     zero.StringFrom(event.Id),
+		},
+		TaskID:    taskID,
+		Declined:  declinedBySelf(event),
+		Free:      event.Transparency == "transparent",
+		Attendees: googleAttendees(event),
+	}
+
+	if allDay {
+		modelEvent.AllDay = zero.BoolFrom(true)
+		modelEvent.EndTime = zero.TimeFrom(endTime)
+	}
+
+	return modelEvent
+}
+
+// googleEventTimes reads when the event starts and ends. All day events only
+// have dates, which are taken as midnights in the user's zone with the end
+// exclusive, the way Google has them.
+func googleEventTimes(event *calendar.Event) (start time.Time, end time.Time, allDay bool) {
+	if event.Start == nil || event.End == nil {
+		return start, end, false
+	}
+
+	if event.Start.DateTime == "" && event.Start.Date != "" {
+		start, _ = time.ParseInLocation(time.DateOnly, event.Start.Date, utils.Location())
+		end, _ = time.ParseInLocation(time.DateOnly, event.End.Date, utils.Location())
+
+		// Events without an end date take their one day
+		if !end.After(start) {
+			end = start.AddDate(0, 0, 1)
+		}
+
+		return start, end, true
+	}
+
+	start, _ = time.Parse(time.RFC3339, event.Start.DateTime)
+	end, _ = time.Parse(time.RFC3339, event.End.DateTime)
+
+	return start, end, false
+}
+
+// googleAttendees names the people invited along with the account's owner,
+// leaving out rooms and other resources.
+func googleAttendees(event *calendar.Event) []string {
+	var attendees []string
+	for _, attendee := range event.Attendees {
+		if attendee.Self || attendee.Resource {
+			continue
+		}
+
+		name := attendee.DisplayName
+		if name == "" {
+			name = attendee.Email
+		}
+
+		attendees = append(attendees, name)
+	}
+
+	return attendees
+}
+
+// declinedBySelf tells whether the account's owner said they won't go.
+func declinedBySelf(event *calendar.Event) bool {
+	for _, attendee := range event.Attendees {
+		if attendee.Self {
+			return attendee.ResponseStatus == "declined"
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,345 @@
+package cal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/pleimann/camel-do/model"
+	"github.com/pleimann/camel-do/utils"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// calendarAPIStandIn answers the events and calendar list requests
+// GoogleProvider makes the way the Calendar API does, a page at a time. Sync
+// tokens other than the current one have expired.
+type calendarAPIStandIn struct {
+	events    []*calendar.Event
+	calendars []*calendar.CalendarListEntry
+	syncToken string
+
+	requests []*http.Request
+}
+
+func (s *calendarAPIStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests = append(s.requests, r)
+	query := r.URL.Query()
+
+	// Google's page size when none is asked for
+	pageSize := 250
+	if maxResults := query.Get("maxResults"); maxResults != "" {
+		pageSize, _ = strconv.Atoi(maxResults)
+	}
+
+	offset, _ := strconv.Atoi(query.Get("pageToken"))
+	nextPageToken := ""
+
+	page := func(total int) (int, int) {
+		end := min(offset+pageSize, total)
+		if end < total {
+			nextPageToken = strconv.Itoa(end)
+		}
+
+		return offset, end
+	}
+
+	var response any
+
+	switch r.URL.Path {
+	case "/users/me/calendarList":
+		start, end := page(len(s.calendars))
+		response = &calendar.CalendarList{Items: s.calendars[start:end], NextPageToken: nextPageToken}
+
+	case "/calendars/primary/events":
+		if syncToken := query.Get("syncToken"); syncToken != "" && syncToken != s.syncToken {
+			w.WriteHeader(http.StatusGone)
+			fmt.Fprint(w, `{"error": {"code": 410, "message": "Sync token is no longer valid, a full sync is required."}}`)
+			return
+		}
+
+		start, end := page(len(s.events))
+		events := &calendar.Events{Items: s.events[start:end], NextPageToken: nextPageToken}
+
+		// The sync token only comes with the last page
+		if nextPageToken == "" {
+			events.NextSyncToken = s.syncToken
+		}
+
+		response = events
+
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func newTestGoogleProvider(t *testing.T, standIn *calendarAPIStandIn) *GoogleProvider {
+	t.Helper()
+
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+
+	service, err := calendar.NewService(context.Background(),
+		option.WithEndpoint(server.URL+"/"),
+		option.WithHTTPClient(server.Client()),
+	)
+
+	if err != nil {
+		t.Fatalf("calendar.NewService() error = %v", err)
+	}
+
+	return &GoogleProvider{service: service}
+}
+
+// busyDay is a day of back to back meetings, more than fit on a page.
+func busyDay(meetings int) []*calendar.Event {
+	start := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+
+	events := make([]*calendar.Event, meetings)
+	for i := range events {
+		events[i] = &calendar.Event{
+			Id:      fmt.Sprintf("meeting%04d", i),
+			Summary: fmt.Sprintf("Meeting %d", i),
+			Start:   &calendar.EventDateTime{DateTime: start.Add(time.Duration(i) * 5 * time.Minute).Format(time.RFC3339)},
+			End:     &calendar.EventDateTime{DateTime: start.Add(time.Duration(i+1) * 5 * time.Minute).Format(time.RFC3339)},
+		}
+	}
+
+	return events
+}
+
+func TestGoogleProviderListChangesPages(t *testing.T) {
+	tests := []struct {
+		name      string
+		meetings  int
+		syncToken string
+		wantPages int
+		wantFull  bool
+	}{
+		{name: "a quiet calendar fits on a page", meetings: 12, wantPages: 1, wantFull: true},
+		{name: "a busy calendar takes several pages", meetings: 2*googleEventsPageSize + 1, wantPages: 3, wantFull: true},
+		{name: "only changes are listed with the sync token", meetings: 3, syncToken: "current", wantPages: 1},
+		{name: "an expired sync token calls for listing everything", meetings: googleEventsPageSize + 1, syncToken: "expired", wantPages: 1 + 2, wantFull: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := &calendarAPIStandIn{events: busyDay(tt.meetings), syncToken: "current"}
+			provider := newTestGoogleProvider(t, standIn)
+
+			changes, err := provider.ListChanges(context.Background(), "primary", tt.syncToken)
+			if err != nil {
+				t.Fatalf("ListChanges() error = %v", err)
+			}
+
+			if len(changes.Events) != tt.meetings {
+				t.Errorf("ListChanges() = %d events, want all %d", len(changes.Events), tt.meetings)
+			}
+
+			if len(standIn.requests) != tt.wantPages {
+				t.Errorf("ListChanges() made %d requests, want %d", len(standIn.requests), tt.wantPages)
+			}
+
+			if changes.Full != tt.wantFull || changes.NextSyncToken != "current" {
+				t.Errorf("ListChanges() full = %v with sync token %q, want %v with current", changes.Full, changes.NextSyncToken, tt.wantFull)
+			}
+
+			for _, r := range standIn.requests {
+				if got := r.URL.Query().Get("maxResults"); got != strconv.Itoa(googleEventsPageSize) {
+					t.Errorf("request asked for pages of %q events, want %d", got, googleEventsPageSize)
+				}
+			}
+		})
+	}
+}
+
+func TestGoogleProviderListCalendarsPages(t *testing.T) {
+	standIn := &calendarAPIStandIn{}
+	for i := range googleCalendarPageSize + 10 {
+		standIn.calendars = append(standIn.calendars, &calendar.CalendarListEntry{
+			Id:         fmt.Sprintf("calendar%d", i),
+			Summary:    fmt.Sprintf("Calendar %d", i),
+			AccessRole: "reader",
+			Primary:    i == 0,
+		})
+	}
+
+	provider := newTestGoogleProvider(t, standIn)
+
+	calendars, err := provider.ListCalendars(context.Background())
+	if err != nil {
+		t.Fatalf("ListCalendars() error = %v", err)
+	}
+
+	if len(calendars) != len(standIn.calendars) || len(standIn.requests) != 2 {
+		t.Fatalf("ListCalendars() = %d calendars in %d requests, want %d in 2", len(calendars), len(standIn.requests), len(standIn.calendars))
+	}
+
+	if !calendars[0].Primary || !calendars[0].Visible || calendars[1].Visible {
+		t.Errorf("ListCalendars() = %+v, want only the primary calendar visible", calendars[:2])
+	}
+}
+
+func TestToModelEvent(t *testing.T) {
+	tests := []struct {
+		name         string
+		event        *calendar.Event
+		wantStart    time.Time
+		wantMinutes  int32
+		wantAllDay   bool
+		wantDays     int
+		wantDeclined bool
+	}{
+		{
+			name: "timed meeting",
+			event: &calendar.Event{
+				Start: &calendar.EventDateTime{DateTime: "2025-06-02T09:00:00-04:00"},
+				End:   &calendar.EventDateTime{DateTime: "2025-06-02T09:30:00-04:00"},
+			},
+			wantStart:   time.Date(2025, 6, 2, 13, 0, 0, 0, time.UTC),
+			wantMinutes: 30,
+			wantDays:    1,
+		},
+		{
+			name: "holiday",
+			event: &calendar.Event{
+				Start: &calendar.EventDateTime{Date: "2025-07-04"},
+				End:   &calendar.EventDateTime{Date: "2025-07-05"},
+			},
+			wantStart:   time.Date(2025, 7, 4, 0, 0, 0, 0, time.Local),
+			wantMinutes: int32(time.Date(2025, 7, 5, 0, 0, 0, 0, time.Local).Sub(time.Date(2025, 7, 4, 0, 0, 0, 0, time.Local)).Minutes()),
+			wantAllDay:  true,
+			wantDays:    1,
+		},
+		{
+			name: "trip",
+			event: &calendar.Event{
+				Start: &calendar.EventDateTime{Date: "2025-06-30"},
+				End:   &calendar.EventDateTime{Date: "2025-07-03"},
+			},
+			wantStart:   time.Date(2025, 6, 30, 0, 0, 0, 0, time.Local),
+			wantMinutes: int32(time.Date(2025, 7, 3, 0, 0, 0, 0, time.Local).Sub(time.Date(2025, 6, 30, 0, 0, 0, 0, time.Local)).Minutes()),
+			wantAllDay:  true,
+			wantDays:    3,
+		},
+		{
+			name: "declined meeting",
+			event: &calendar.Event{
+				Start: &calendar.EventDateTime{DateTime: "2025-06-02T15:00:00Z"},
+				End:   &calendar.EventDateTime{DateTime: "2025-06-02T16:00:00Z"},
+				Attendees: []*calendar.EventAttendee{
+					{Email: "organizer@example.com", ResponseStatus: "accepted", Organizer: true},
+					{Email: "me@example.com", ResponseStatus: "declined", Self: true},
+				},
+			},
+			wantStart:    time.Date(2025, 6, 2, 15, 0, 0, 0, time.UTC),
+			wantMinutes:  60,
+			wantDays:     1,
+			wantDeclined: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := toModelEvent(tt.event)
+
+			if !event.StartTime.Time.Equal(tt.wantStart) || event.Duration.Int32 != tt.wantMinutes {
+				t.Errorf("toModelEvent() starts %s for %d minutes, want %s for %d", event.StartTime.Time, event.Duration.Int32, tt.wantStart, tt.wantMinutes)
+			}
+
+			if event.AllDay.Bool != tt.wantAllDay || event.Days() != tt.wantDays {
+				t.Errorf("toModelEvent() all day = %v for %d days, want %v for %d", event.AllDay.Bool, event.Days(), tt.wantAllDay, tt.wantDays)
+			}
+
+			if event.Declined != tt.wantDeclined {
+				t.Errorf("toModelEvent() declined = %v, want %v", event.Declined, tt.wantDeclined)
+			}
+		})
+	}
+}
+
+func TestEventsByDay(t *testing.T) {
+	monday := time.Date(2025, 6, 2, 0, 0, 0, 0, time.Local)
+
+	events := model.NewEventList()
+	for _, event := range []*calendar.Event{
+		{Id: "standup", Start: &calendar.EventDateTime{DateTime: monday.Add(9 * time.Hour).Format(time.RFC3339)}, End: &calendar.EventDateTime{DateTime: monday.Add(9*time.Hour + 15*time.Minute).Format(time.RFC3339)}},
+		{Id: "offsite", Start: &calendar.EventDateTime{Date: "2025-06-03"}, End: &calendar.EventDateTime{Date: "2025-06-05"}},
+		{Id: "late", Start: &calendar.EventDateTime{DateTime: monday.AddDate(0, 0, 4).Add(23 * time.Hour).Format(time.RFC3339)}, End: &calendar.EventDateTime{DateTime: monday.AddDate(0, 0, 5).Add(time.Hour).Format(time.RFC3339)}},
+	} {
+		events.Push(toModelEvent(event))
+	}
+
+	want := [][]string{{"standup"}, {"offsite"}, {"offsite"}, nil, {"late"}, {"late"}, nil}
+
+	byDay := eventsByDay(events, monday, 7)
+
+	for i, day := range byDay {
+		var ids []string
+		for event := range day.All() {
+			ids = append(ids, event.ID)
+		}
+
+		if fmt.Sprint(ids) != fmt.Sprint(want[i]) {
+			t.Errorf("day %d has %v, want %v", i, ids, want[i])
+		}
+	}
+}
+
+func TestEventsByDayAcrossDaylightSaving(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utils.SetLocation(newYork)
+	t.Cleanup(func() { utils.SetLocation(nil) })
+
+	// The weekend New York's clocks go forward, London's don't until later
+	saturday := time.Date(2025, 3, 8, 0, 0, 0, 0, newYork)
+
+	events := model.NewEventList()
+	for _, event := range []*calendar.Event{
+		// Sunday in London, still Saturday evening in New York
+		{Id: "call", Start: &calendar.EventDateTime{DateTime: "2025-03-09T03:30:00Z"}, End: &calendar.EventDateTime{DateTime: "2025-03-09T04:00:00Z"}},
+		// The all day event takes the short Sunday, not the start of Monday
+		{Id: "sunday", Start: &calendar.EventDateTime{Date: "2025-03-09"}, End: &calendar.EventDateTime{Date: "2025-03-10"}},
+		{Id: "overnight", Start: &calendar.EventDateTime{DateTime: "2025-03-09T23:00:00-04:00"}, End: &calendar.EventDateTime{DateTime: "2025-03-10T01:00:00-04:00"}},
+		// Monday breakfast in London is before dawn in New York
+		{Id: "breakfast", Start: &calendar.EventDateTime{DateTime: "2025-03-10T08:00:00+00:00"}, End: &calendar.EventDateTime{DateTime: "2025-03-10T09:00:00+00:00"}},
+	} {
+		events.Push(toModelEvent(event))
+	}
+
+	want := [][]string{{"call"}, {"sunday", "overnight"}, {"overnight", "breakfast"}}
+
+	byDay := eventsByDay(events, saturday, 3)
+
+	for i, day := range byDay {
+		var ids []string
+		for event := range day.All() {
+			ids = append(ids, event.ID)
+		}
+
+		if fmt.Sprint(ids) != fmt.Sprint(want[i]) {
+			t.Errorf("day %d has %v, want %v", i, ids, want[i])
+		}
+	}
+
+	wantDays := map[string]int{"call": 1, "sunday": 1, "overnight": 2, "breakfast": 1}
+	for event := range events.All() {
+		if days := event.Days(); days != wantDays[event.ID] {
+			t.Errorf("%s covers %d days, want %d", event.ID, days, wantDays[event.ID])
+		}
+	}
+}
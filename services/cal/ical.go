@@ -0,0 +1,410 @@
+package cal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guregu/null/v6/zero"
+	"github.com/pleimann/camel-do/model"
+	"github.com/pleimann/camel-do/utils"
+)
+
+// icalProperty is a content line of an iCalendar (RFC 5545) component, like
+// DTSTART;TZID=Europe/Berlin:20250301T090000.
+type icalProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// icalComponent is a BEGIN/END block of an iCalendar, like a VEVENT.
+type icalComponent struct {
+	Name       string
+	Properties []icalProperty
+	Components []*icalComponent
+}
+
+// Property finds the first property of the name, nil when there is none.
+func (c *icalComponent) Property(name string) *icalProperty {
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			return &c.Properties[i]
+		}
+	}
+
+	return nil
+}
+
+// PropertyList finds every property of the name, like the EXDATEs of an event.
+func (c *icalComponent) PropertyList(name string) []icalProperty {
+	var properties []icalProperty
+	for _, property := range c.Properties {
+		if property.Name == name {
+			properties = append(properties, property)
+		}
+	}
+
+	return properties
+}
+
+// Text is the unescaped value of the named property, empty when there is none.
+func (c *icalComponent) Text(name string) string {
+	if property := c.Property(name); property != nil {
+		return unescapeICalText(property.Value)
+	}
+
+	return ""
+}
+
+// Find lists the nested components of the name, at any depth.
+func (c *icalComponent) Find(name string) []*icalComponent {
+	var found []*icalComponent
+	for _, component := range c.Components {
+		if component.Name == name {
+			found = append(found, component)
+		}
+
+		found = append(found, component.Find(name)...)
+	}
+
+	return found
+}
+
+// parseICalendar reads iCalendar data into its components. Several calendars
+// one after the other are read as children of one root.
+func parseICalendar(r io.Reader) (*icalComponent, error) {
+	root := &icalComponent{}
+	open := []*icalComponent{root}
+
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading iCalendar: %w", err)
+	}
+
+	for number, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		property, err := parseICalProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("reading iCalendar line %d: %w", number+1, err)
+		}
+
+		current := open[len(open)-1]
+
+		switch property.Name {
+		case "BEGIN":
+			component := &icalComponent{Name: strings.ToUpper(property.Value)}
+			current.Components = append(current.Components, component)
+			open = append(open, component)
+
+		case "END":
+			if len(open) == 1 || current.Name != strings.ToUpper(property.Value) {
+				return nil, fmt.Errorf("reading iCalendar line %d: unexpected END:%s", number+1, property.Value)
+			}
+
+			open = open[:len(open)-1]
+
+		default:
+			current.Properties = append(current.Properties, property)
+		}
+	}
+
+	if len(open) > 1 {
+		return nil, fmt.Errorf("reading iCalendar: %s is never closed", open[len(open)-1].Name)
+	}
+
+	return root, nil
+}
+
+// unfoldICalLines splits the data into content lines, joining the long lines
+// which were folded onto several.
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+// parseICalProperty splits a content line into its name, parameters and value.
+// Parameter values may be quoted to hold colons and semicolons.
+func parseICalProperty(line string) (icalProperty, error) {
+	head, value, ok := cutUnquoted(line, ':')
+	if !ok {
+		return icalProperty{}, errors.New("a content line needs a colon between its name and value")
+	}
+
+	property := icalProperty{Params: map[string]string{}, Value: value}
+
+	name, params, _ := cutUnquoted(head, ';')
+	property.Name = strings.ToUpper(name)
+
+	for params != "" {
+		var param string
+		param, params, _ = cutUnquoted(params, ';')
+
+		key, value, _ := strings.Cut(param, "=")
+		property.Params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+
+	return property, nil
+}
+
+// cutUnquoted is strings.Cut for the first separator outside double quotes.
+func cutUnquoted(s string, separator rune) (string, string, bool) {
+	quoted := false
+	for i, r := range s {
+		if r == '"' {
+			quoted = !quoted
+
+		} else if r == separator && !quoted {
+			return s[:i], s[i+1:], true
+		}
+	}
+
+	return s, "", false
+}
+
+var icalTextEscapes = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func unescapeICalText(value string) string {
+	return icalTextEscapes.Replace(value)
+}
+
+// Time reads a DATE or DATE-TIME property. Times in UTC end in Z, those with a
+// TZID are in that zone, and floating times are in the user's zone. Dates are
+// midnight there.
+func (p *icalProperty) Time() (time.Time, bool, error) {
+	value := strings.TrimSpace(p.Value)
+
+	if p.Params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		date, err := time.ParseInLocation("20060102", value, utils.Location())
+		return date, true, err
+	}
+
+	location := utils.Location()
+	if strings.HasSuffix(value, "Z") {
+		location = time.UTC
+		value = strings.TrimSuffix(value, "Z")
+
+	} else if tzid := p.Params["TZID"]; tzid != "" {
+		location = icalLocation(tzid)
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, location)
+
+	return t, false, err
+}
+
+// Times reads a property listing several dates or times, like EXDATE.
+func (p *icalProperty) Times() ([]time.Time, error) {
+	var times []time.Time
+	for _, value := range strings.Split(p.Value, ",") {
+		single := icalProperty{Name: p.Name, Params: p.Params, Value: value}
+
+		t, _, err := single.Time()
+		if err != nil {
+			return nil, err
+		}
+
+		times = append(times, t)
+	}
+
+	return times, nil
+}
+
+// icalLocation finds the zone of a TZID. Some calendars prefix the zone name
+// with a path, like /mozilla.org/20050126_1/America/New_York, which is dropped.
+// Zones Go doesn't know are taken to be the user's zone.
+func icalLocation(tzid string) *time.Location {
+	for name := tzid; name != ""; {
+		if zone, err := time.LoadLocation(name); err == nil {
+			return zone
+		}
+
+		_, rest, found := strings.Cut(strings.TrimPrefix(name, "/"), "/")
+		if !found {
+			break
+		}
+
+		name = rest
+	}
+
+	return utils.Location()
+}
+
+var icalDurationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseICalDuration reads a DURATION value like PT1H30M or P1D.
+func parseICalDuration(value string) (time.Duration, error) {
+	match := icalDurationPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, fmt.Errorf("%q is not an iCalendar duration", value)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+
+	var duration time.Duration
+	for i, unit := range units {
+		if match[i+2] != "" {
+			n, _ := strconv.Atoi(match[i+2])
+			duration += time.Duration(n) * unit
+		}
+	}
+
+	if match[1] == "-" {
+		duration = -duration
+	}
+
+	return duration, nil
+}
+
+// icalAttendees names the attendees of the event other than the one with the
+// address, by their common name when they have one.
+func icalAttendees(vevent *icalComponent, address string) []string {
+	var attendees []string
+	for _, attendee := range vevent.PropertyList("ATTENDEE") {
+		email, _ := strings.CutPrefix(strings.ToLower(attendee.Value), "mailto:")
+
+		if strings.EqualFold(email, address) || attendee.Params["CUTYPE"] == "RESOURCE" || attendee.Params["CUTYPE"] == "ROOM" {
+			continue
+		}
+
+		name := attendee.Params["CN"]
+		if name == "" {
+			name = email
+		}
+
+		attendees = append(attendees, name)
+	}
+
+	return attendees
+}
+
+// icalDeclinedBy tells whether the attendee with the address declined the
+// event. Accounts signed in with something other than their address never
+// match, as CalDAV doesn't say which attendee the account is.
+func icalDeclinedBy(vevent *icalComponent, address string) bool {
+	for _, attendee := range vevent.PropertyList("ATTENDEE") {
+		email, _ := strings.CutPrefix(strings.ToLower(attendee.Value), "mailto:")
+
+		if email != "" && strings.EqualFold(email, address) {
+			return strings.EqualFold(attendee.Params["PARTSTAT"], "DECLINED")
+		}
+	}
+
+	return false
+}
+
+// icalUID is the UID of the event. Events without one, which some calendars
+// leave out, get one made from their start and summary instead.
+func icalUID(vevent *icalComponent) string {
+	if uid := vevent.Text("UID"); uid != "" {
+		return uid
+	}
+
+	var start string
+	if dtstart := vevent.Property("DTSTART"); dtstart != nil {
+		start = dtstart.Value
+	}
+
+	sum := sha256.Sum256([]byte(start + "\n" + vevent.Text("SUMMARY")))
+
+	return hex.EncodeToString(sum[:16])
+}
+
+// icalEvent turns a VEVENT into an event. Instances of recurring events are
+// told apart by their RECURRENCE-ID.
+func icalEvent(vevent *icalComponent) (model.Event, error) {
+	dtstart := vevent.Property("DTSTART")
+	if dtstart == nil {
+		return model.Event{}, errors.New("the event has no start")
+	}
+
+	start, allDay, err := dtstart.Time()
+	if err != nil {
+		return model.Event{}, fmt.Errorf("reading the start of the event: %w", err)
+	}
+
+	// Without an end or duration a timed event takes no time and an all day
+	// one takes its day
+	end := start
+	if allDay {
+		end = start.AddDate(0, 0, 1)
+	}
+
+	if dtend := vevent.Property("DTEND"); dtend != nil {
+		if end, _, err = dtend.Time(); err != nil {
+			return model.Event{}, fmt.Errorf("reading the end of the event: %w", err)
+		}
+
+	} else if duration := vevent.Property("DURATION"); duration != nil {
+		length, err := parseICalDuration(duration.Value)
+		if err != nil {
+			return model.Event{}, fmt.Errorf("reading the duration of the event: %w", err)
+		}
+
+		// Days and weeks of all day events are calendar days, which daylight
+		// saving changes can make longer or shorter than 24 hours
+		if allDay {
+			day := 24 * time.Hour
+			end = start.AddDate(0, 0, int(length/day)).Add(length % day)
+		} else {
+			end = start.Add(length)
+		}
+	}
+
+	id := icalUID(vevent)
+	if recurrenceID := vevent.Property("RECURRENCE-ID"); recurrenceID != nil {
+		id += "/" + recurrenceID.Value
+	}
+
+	event := model.Event{
+		Task: model.Task{
+			ID:          id,
+			Title:       zero.StringFrom(vevent.Text("SUMMARY")),
+			Description: zero.StringFrom(vevent.Text("DESCRIPTION")),
+			StartTime:   zero.TimeFrom(start),
+			Duration:    zero.Int32From(int32(end.Sub(start).Minutes())),
+		},
+	}
+
+	if allDay {
+		event.AllDay = zero.BoolFrom(true)
+		event.EndTime = zero.TimeFrom(end)
+	}
+
+	event.Free = vevent.Text("TRANSP") == "TRANSPARENT"
+
+	if created := vevent.Property("CREATED"); created != nil {
+		event.CreatedAt, _, _ = created.Time()
+	}
+
+	if modified := vevent.Property("LAST-MODIFIED"); modified != nil {
+		event.UpdatedAt, _, _ = modified.Time()
+	}
+
+	return event, nil
+}
@@ -0,0 +1,301 @@
+package cal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/guregu/null/v6/zero"
+	"github.com/pleimann/camel-do/model"
+)
+
+// ReadICS reads the events of an iCalendar file, like a conference schedule,
+// along with the name the file gives the calendar, if any. Recurring events
+// are expanded into their instances up to until, with the instances moved or
+// cancelled on their own taking the place of the ones they change. A zero
+// until reads each series once, as its first instance. Events which can't be
+// read are skipped, and events sharing an ID are numbered to keep them apart.
+func ReadICS(r io.Reader, until time.Time) (string, []model.Event, error) {
+	root, err := parseICalendar(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var name string
+	for _, vcalendar := range root.Find("VCALENDAR") {
+		if name = vcalendar.Text("X-WR-CALNAME"); name != "" {
+			break
+		}
+	}
+
+	vevents := root.Find("VEVENT")
+
+	// Instances changed on their own replace those the series would have
+	overridden := map[string]bool{}
+	for _, vevent := range vevents {
+		if recurrenceID := vevent.Property("RECURRENCE-ID"); recurrenceID != nil {
+			if start, _, err := recurrenceID.Time(); err == nil {
+				overridden[instanceKey(icalUID(vevent), start)] = true
+			}
+		}
+	}
+
+	var events []model.Event
+	for _, vevent := range vevents {
+		if vevent.Text("STATUS") == "CANCELLED" {
+			continue
+		}
+
+		event, err := icalEvent(vevent)
+		if err != nil {
+			slog.Warn("skipping unreadable iCalendar event", "uid", icalUID(vevent), "error", err)
+			continue
+		}
+
+		// Files aren't anyone's in particular, so everyone invited is listed
+		event.Attendees = icalAttendees(vevent, "")
+
+		if vevent.Property("RECURRENCE-ID") != nil {
+			if !until.IsZero() {
+				events = append(events, event)
+			}
+
+			continue
+		}
+
+		if until.IsZero() {
+			events = append(events, event)
+			continue
+		}
+
+		instances, err := expandICalEvent(vevent, event, until)
+		if err != nil {
+			slog.Warn("importing only the first instance of a recurring event", "uid", icalUID(vevent), "error", err)
+			instances = []model.Event{event}
+		}
+
+		for _, instance := range instances {
+			if !overridden[instanceKey(icalUID(vevent), instance.StartTime.Time)] {
+				events = append(events, instance)
+			}
+		}
+	}
+
+	seen := map[string]int{}
+	for i, event := range events {
+		seen[event.ID]++
+
+		if n := seen[event.ID]; n > 1 {
+			slog.Warn("numbering iCalendar event with a repeated UID", "uid", event.ID, "n", n)
+			events[i].ID = fmt.Sprintf("%s#%d", event.ID, n)
+		}
+	}
+
+	return name, events, nil
+}
+
+func instanceKey(uid string, start time.Time) string {
+	return fmt.Sprintf("%s/%d", uid, start.Unix())
+}
+
+// expandICalEvent lists the instances of an event up to until, from its RRULE
+// and RDATEs less its EXDATEs. Events which don't recur are their only
+// instance. Instances are told apart by adding their start to the ID, the way
+// it's written in a RECURRENCE-ID.
+func expandICalEvent(vevent *icalComponent, event model.Event, until time.Time) ([]model.Event, error) {
+	rrule := vevent.Property("RRULE")
+	rdates := vevent.PropertyList("RDATE")
+
+	if rrule == nil && len(rdates) == 0 {
+		return []model.Event{event}, nil
+	}
+
+	start := event.StartTime.Time
+	starts := []time.Time{start}
+
+	if rrule != nil {
+		rule, err := parseICalRecurrence(rrule.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		starts = rule.occurrences(start, until)
+	}
+
+	for _, rdate := range rdates {
+		times, err := rdate.Times()
+		if err != nil {
+			return nil, fmt.Errorf("reading RDATE: %w", err)
+		}
+
+		for _, t := range times {
+			if !t.After(until) {
+				starts = append(starts, t)
+			}
+		}
+	}
+
+	for _, exdate := range vevent.PropertyList("EXDATE") {
+		times, err := exdate.Times()
+		if err != nil {
+			return nil, fmt.Errorf("reading EXDATE: %w", err)
+		}
+
+		starts = slices.DeleteFunc(starts, func(start time.Time) bool {
+			return slices.ContainsFunc(times, start.Equal)
+		})
+	}
+
+	slices.SortFunc(starts, time.Time.Compare)
+	starts = slices.CompactFunc(starts, time.Time.Equal)
+
+	// All day events keep their number of days rather than their length, which
+	// daylight saving changes can stretch or shrink
+	days := event.Days()
+	utc := strings.HasSuffix(vevent.Property("DTSTART").Value, "Z")
+
+	instances := make([]model.Event, 0, len(starts))
+	for _, start := range starts {
+		instance := event
+		instance.ID = event.ID + "/" + recurrenceIDValue(start, event.AllDay.Bool, utc)
+		instance.StartTime = zero.TimeFrom(start)
+
+		if event.AllDay.Bool {
+			end := start.AddDate(0, 0, days)
+
+			instance.EndTime = zero.TimeFrom(end)
+			instance.Duration = zero.Int32From(int32(end.Sub(start).Minutes()))
+		}
+
+		instances = append(instances, instance)
+	}
+
+	return instances, nil
+}
+
+// recurrenceIDValue writes the start of an instance the way its RECURRENCE-ID
+// would have it.
+func recurrenceIDValue(start time.Time, allDay bool, utc bool) string {
+	switch {
+	case allDay:
+		return start.Format("20060102")
+
+	case utc:
+		return start.UTC().Format("20060102T150405Z")
+
+	default:
+		return start.Format("20060102T150405")
+	}
+}
+
+// WriteICS writes the scheduled tasks as the events of an iCalendar file named
+// name. Times are written in UTC so the file needs no time zone definitions,
+// and all day tasks as dates. Each event's UID is made from its task's ID, so
+// importing the file again, or subscribing to it, updates the events rather
+// than adding them twice.
+func WriteICS(w io.Writer, name string, tasks iter.Seq[model.Task], now time.Time) error {
+	writer := bufio.NewWriter(w)
+	line := func(name string, value string) {
+		writeICalLine(writer, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//camel-do//camel-do//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+
+	if name != "" {
+		line("X-WR-CALNAME", escapeICalText(name))
+	}
+
+	for task := range tasks {
+		if !task.StartTime.Valid || task.Status == model.Cancelled {
+			continue
+		}
+
+		line("BEGIN", "VEVENT")
+		line("UID", task.ID+"@camel-do")
+		line("DTSTAMP", icalUTC(now))
+
+		if !task.CreatedAt.IsZero() {
+			line("CREATED", icalUTC(task.CreatedAt))
+		}
+
+		if !task.UpdatedAt.IsZero() {
+			line("LAST-MODIFIED", icalUTC(task.UpdatedAt))
+		}
+
+		line("SUMMARY", escapeICalText(taskSummary(&task)))
+
+		if task.Description.String != "" {
+			line("DESCRIPTION", escapeICalText(task.Description.String))
+		}
+
+		if task.AllDay.Bool {
+			line("DTSTART;VALUE=DATE", task.StartTime.Time.Format("20060102"))
+			line("DTEND;VALUE=DATE", task.End().Format("20060102"))
+
+		} else {
+			line("DTSTART", icalUTC(task.StartTime.Time))
+			line("DTEND", icalUTC(task.End()))
+		}
+
+		// Done tasks no longer keep the time busy
+		if task.Status == model.Done {
+			line("TRANSP", "TRANSPARENT")
+		}
+
+		if task.ReminderOffset.Valid {
+			line("BEGIN", "VALARM")
+			line("ACTION", "DISPLAY")
+			line("DESCRIPTION", escapeICalText(task.Title.String))
+			line("TRIGGER", fmt.Sprintf("-PT%dM", task.ReminderOffset.Int32))
+			line("END", "VALARM")
+		}
+
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+
+	return writer.Flush()
+}
+
+func icalUTC(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+var icalTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "")
+
+func escapeICalText(value string) string {
+	return icalTextEscaper.Replace(value)
+}
+
+// writeICalLine writes a content line, folding it onto continuation lines so
+// none is longer than the 75 bytes iCalendar allows, without splitting any
+// character.
+func writeICalLine(w *bufio.Writer, line string) {
+	const maxLength = 75
+
+	for limit := maxLength; len(line) > limit; limit = maxLength - 1 {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+
+		line = line[cut:]
+	}
+
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
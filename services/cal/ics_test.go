@@ -0,0 +1,290 @@
+package cal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guregu/null/v6/zero"
+	"github.com/pleimann/camel-do/model"
+	"github.com/pleimann/camel-do/utils"
+)
+
+func TestReadICSRecurrence(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data isn't available", err)
+	}
+
+	local := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	}
+
+	inNewYork := func(year int, month time.Month, day int, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, newYork)
+	}
+
+	tests := []struct {
+		name   string
+		vevent string
+		until  time.Time
+		want   []time.Time
+	}{
+		{
+			name: "weekly on two days keeps its time of day over daylight saving",
+			vevent: `DTSTART;TZID=America/New_York:20250303T100000
+DURATION:PT1H
+RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4`,
+			until: local(2026, 1, 1),
+			want:  []time.Time{inNewYork(2025, 3, 3, 10), inNewYork(2025, 3, 5, 10), inNewYork(2025, 3, 10, 10), inNewYork(2025, 3, 12, 10)},
+		},
+		{
+			name: "monthly on the last Friday",
+			vevent: `DTSTART;TZID=America/New_York:20250131T150000
+RRULE:FREQ=MONTHLY;BYDAY=-1FR;UNTIL=20250501T000000Z`,
+			until: local(2026, 1, 1),
+			want:  []time.Time{inNewYork(2025, 1, 31, 15), inNewYork(2025, 2, 28, 15), inNewYork(2025, 3, 28, 15), inNewYork(2025, 4, 25, 15)},
+		},
+		{
+			name: "monthly on the 31st skips shorter months",
+			vevent: `DTSTART;TZID=America/New_York:20250131T090000
+RRULE:FREQ=MONTHLY;COUNT=3`,
+			until: local(2026, 1, 1),
+			want:  []time.Time{inNewYork(2025, 1, 31, 9), inNewYork(2025, 3, 31, 9), inNewYork(2025, 5, 31, 9)},
+		},
+		{
+			name: "yearly on a leap day",
+			vevent: `DTSTART;VALUE=DATE:20240229
+RRULE:FREQ=YEARLY`,
+			until: local(2030, 1, 1),
+			want:  []time.Time{local(2024, 2, 29), local(2028, 2, 29)},
+		},
+		{
+			name: "every other day without an excluded one, up to the horizon",
+			vevent: `DTSTART:20250601T120000Z
+RRULE:FREQ=DAILY;INTERVAL=2
+EXDATE:20250603T120000Z`,
+			until: time.Date(2025, 6, 8, 0, 0, 0, 0, time.UTC),
+			want:  []time.Time{time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), time.Date(2025, 6, 5, 12, 0, 0, 0, time.UTC), time.Date(2025, 6, 7, 12, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "without a horizon the series is read once",
+			vevent: `DTSTART:20250601T120000Z
+RRULE:FREQ=DAILY`,
+			want: []time.Time{time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "unsupported rules keep the first instance",
+			vevent: `DTSTART:20250601T120000Z
+RRULE:FREQ=HOURLY;COUNT=3`,
+			until: local(2026, 1, 1),
+			want:  []time.Time{time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ics := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:series\nSUMMARY:Series\n" + tt.vevent + "\nEND:VEVENT\nEND:VCALENDAR\n"
+
+			_, events, err := ReadICS(strings.NewReader(ics), tt.until)
+			if err != nil {
+				t.Fatalf("ReadICS() error = %v", err)
+			}
+
+			if len(events) != len(tt.want) {
+				t.Fatalf("ReadICS() = %d events, want %d", len(events), len(tt.want))
+			}
+
+			for i, event := range events {
+				if !event.StartTime.Time.Equal(tt.want[i]) || event.StartTime.Time.Hour() != tt.want[i].Hour() {
+					t.Errorf("instance %d starts %s, want %s", i, event.StartTime.Time, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadICSEventIDs(t *testing.T) {
+	ics := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+SUMMARY:Keynote
+DTSTART:20250601T090000Z
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Workshop
+DTSTART:20250601T140000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:talk
+SUMMARY:First talk
+DTSTART:20250602T090000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:talk
+SUMMARY:Second talk
+DTSTART:20250602T100000Z
+END:VEVENT
+END:VCALENDAR
+`
+
+	_, events, err := ReadICS(strings.NewReader(ics), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ReadICS() error = %v", err)
+	}
+
+	if len(events) != 4 {
+		t.Fatalf("ReadICS() = %d events, want 4", len(events))
+	}
+
+	seen := map[string]bool{}
+	for _, event := range events {
+		if event.ID == "" || seen[event.ID] {
+			t.Errorf("%s has ID %q, want one of its own", event.Title.String, event.ID)
+		}
+
+		seen[event.ID] = true
+	}
+
+	_, again, err := ReadICS(strings.NewReader(ics), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ReadICS() error = %v", err)
+	}
+
+	if again[0].ID != events[0].ID {
+		t.Errorf("event without a UID read as %q then %q, want the same ID each time", events[0].ID, again[0].ID)
+	}
+}
+
+func TestReadICSAllDayDuration(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data isn't available", err)
+	}
+
+	utils.SetLocation(newYork)
+	t.Cleanup(func() { utils.SetLocation(nil) })
+
+	// Daylight saving starts during the week
+	ics := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:retreat\nSUMMARY:Retreat\nDTSTART;VALUE=DATE:20250306\nDURATION:P1W\nEND:VEVENT\nEND:VCALENDAR\n"
+
+	_, events, err := ReadICS(strings.NewReader(ics), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(events) != 1 {
+		t.Fatalf("ReadICS() = %d events, %v, want the retreat", len(events), err)
+	}
+
+	want := time.Date(2025, 3, 13, 0, 0, 0, 0, newYork)
+	if end := events[0].EndTime.Time; !end.Equal(want) {
+		t.Errorf("retreat ends %s, want %s", end, want)
+	}
+}
+
+func TestReadICSMovedInstance(t *testing.T) {
+	ics := `BEGIN:VCALENDAR
+X-WR-CALNAME:Conference
+BEGIN:VEVENT
+UID:keynote
+DTSTART:20250601T090000Z
+DTEND:20250601T100000Z
+SUMMARY:Keynote
+RRULE:FREQ=DAILY;COUNT=3
+END:VEVENT
+BEGIN:VEVENT
+UID:keynote
+RECURRENCE-ID:20250602T090000Z
+DTSTART:20250602T140000Z
+DTEND:20250602T150000Z
+SUMMARY:Keynote (moved)
+END:VEVENT
+BEGIN:VEVENT
+UID:keynote
+RECURRENCE-ID:20250603T090000Z
+DTSTART:20250603T090000Z
+STATUS:CANCELLED
+END:VEVENT
+END:VCALENDAR
+`
+
+	name, events, err := ReadICS(strings.NewReader(ics), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ReadICS() error = %v", err)
+	}
+
+	if name != "Conference" {
+		t.Errorf("ReadICS() name = %q, want Conference", name)
+	}
+
+	want := map[string]string{
+		"keynote/20250601T090000Z": "Keynote",
+		"keynote/20250602T090000Z": "Keynote (moved)",
+	}
+
+	if len(events) != len(want) {
+		t.Fatalf("ReadICS() = %d events, want %d", len(events), len(want))
+	}
+
+	for _, event := range events {
+		if title, ok := want[event.ID]; !ok || title != event.Title.String {
+			t.Errorf("ReadICS() has %s %q, want %v", event.ID, event.Title.String, want)
+		}
+	}
+}
+
+func TestWriteICSReadsBack(t *testing.T) {
+	start := time.Date(2025, 3, 9, 1, 30, 0, 0, time.UTC)
+	description := "First line\n" + strings.Repeat("ünïcödé ", 20)
+
+	reminded := model.Task{
+		ID:          "01TASK",
+		Title:       zero.StringFrom("Write the talk; slides, notes"),
+		Description: zero.StringFrom(description),
+		StartTime:   zero.TimeFrom(start),
+		Duration:    zero.Int32From(90),
+	}
+	reminded.ReminderOffset.Int32, reminded.ReminderOffset.Valid = 15, true
+
+	tasks := model.NewTaskList()
+	tasks.Push(reminded)
+	tasks.Push(model.Task{
+		ID:        "01TRIP",
+		Title:     zero.StringFrom("Conference"),
+		StartTime: zero.TimeFrom(time.Date(2025, 3, 10, 0, 0, 0, 0, time.Local)),
+		AllDay:    zero.BoolFrom(true),
+		EndTime:   zero.TimeFrom(time.Date(2025, 3, 13, 0, 0, 0, 0, time.Local)),
+	})
+	tasks.Push(model.Task{ID: "01BACKLOG", Title: zero.StringFrom("Not scheduled")})
+
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, "Talks", tasks.All(), start); err != nil {
+		t.Fatalf("WriteICS() error = %v", err)
+	}
+
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line %q is longer than 75 bytes", line)
+		}
+	}
+
+	name, events, err := ReadICS(&buf, start)
+	if err != nil {
+		t.Fatalf("ReadICS() error = %v", err)
+	}
+
+	if name != "Talks" || len(events) != 2 {
+		t.Fatalf("ReadICS() = %q with %d events, want Talks with the 2 scheduled tasks", name, len(events))
+	}
+
+	talk, trip := events[0], events[1]
+
+	if talk.ID != "01TASK@camel-do" || talk.Title.String != "Write the talk; slides, notes" || talk.Description.String != description {
+		t.Errorf("timed task read back as %+v", talk.Task)
+	}
+
+	if !talk.StartTime.Time.Equal(start) || talk.Duration.Int32 != 90 {
+		t.Errorf("timed task read back at %s for %d minutes", talk.StartTime.Time, talk.Duration.Int32)
+	}
+
+	if !trip.AllDay.Bool || trip.Days() != 3 || trip.StartTime.Time.Day() != 10 {
+		t.Errorf("all day task read back as %s for %d days", trip.StartTime.Time, trip.Days())
+	}
+}
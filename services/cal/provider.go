@@ -0,0 +1,31 @@
+package cal
+
+import (
+	"context"
+
+	"github.com/pleimann/camel-do/model"
+)
+
+// CalendarProvider is a calendar service events are synced from, like Google
+// Calendar or a CalDAV server.
+type CalendarProvider interface {
+	// Name identifies the provider the calendars it lists belong to
+	Name() string
+
+	// ListCalendars lists the calendars of the account
+	ListCalendars(ctx context.Context) ([]model.CalendarSettings, error)
+
+	// ListChanges lists the events of the calendar changed since the sync token
+	// was handed out. Without a token, or when the provider can't tell what
+	// changed, it lists all of them.
+	ListChanges(ctx context.Context, calendarID string, syncToken string) (*EventChanges, error)
+}
+
+// EventChanges are the events added or changed since the last sync, along
+// with the IDs of those cancelled.
+type EventChanges struct {
+	Events        []model.Event
+	Cancelled     []string
+	NextSyncToken string
+	Full          bool // Events are all the calendar has, replacing those cached
+}
@@ -0,0 +1,324 @@
+package cal
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRecurrences caps how many instances of a recurring event are expanded,
+// for rules which go on forever.
+const maxRecurrences = 1000
+
+// icalRecurrence is an RRULE (RFC 5545 section 3.3.10). The rules calendars
+// actually use are covered: daily, weekly, monthly and yearly repeats picking
+// days by weekday, day of the month and month.
+type icalRecurrence struct {
+	Frequency  string
+	Interval   int
+	Count      int       // Instances there are in all, counting the first, unlimited when 0
+	Until      time.Time // Last time an instance can start at, unlimited when zero
+	ByDay      []icalWeekday
+	ByMonthDay []int // Days of the month, counting back from its end when negative
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+// icalWeekday is a day of a BYDAY list, like MO, 2TU or -1FR.
+type icalWeekday struct {
+	Ordinal int // Which of its weekdays in the month, counting back from the end when negative, every one when 0
+	Weekday time.Weekday
+}
+
+var icalWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// parseICalRecurrence reads an RRULE value like FREQ=WEEKLY;BYDAY=MO,WE.
+// Rules using parts it can't expand are refused rather than expanded wrong.
+func parseICalRecurrence(value string) (icalRecurrence, error) {
+	rule := icalRecurrence{Interval: 1, WeekStart: time.Monday}
+
+	for _, part := range strings.Split(strings.TrimSpace(value), ";") {
+		key, value, _ := strings.Cut(part, "=")
+
+		var err error
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Frequency = strings.ToUpper(value)
+
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err == nil && rule.Interval < 1 {
+				err = fmt.Errorf("interval %d is less than 1", rule.Interval)
+			}
+
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(value)
+
+		case "UNTIL":
+			until := icalProperty{Value: value}
+
+			var allDay bool
+			if rule.Until, allDay, err = until.Time(); allDay {
+				// The last day is included whatever the time of day
+				rule.Until = rule.Until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := icalWeekdays[strings.ToUpper(day[max(len(day)-2, 0):])]
+				if !ok {
+					return rule, fmt.Errorf("%q is not a weekday", day)
+				}
+
+				ordinal := 0
+				if prefix := day[:len(day)-2]; prefix != "" {
+					if ordinal, err = strconv.Atoi(prefix); err != nil {
+						return rule, fmt.Errorf("%q is not a weekday", day)
+					}
+				}
+
+				rule.ByDay = append(rule.ByDay, icalWeekday{Ordinal: ordinal, Weekday: weekday})
+			}
+
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return rule, fmt.Errorf("%q is not a day of the month", day)
+				}
+
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+
+		case "BYMONTH":
+			for _, month := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(month)
+				if err != nil || n < 1 || n > 12 {
+					return rule, fmt.Errorf("%q is not a month", month)
+				}
+
+				rule.ByMonth = append(rule.ByMonth, time.Month(n))
+			}
+
+		case "WKST":
+			weekday, ok := icalWeekdays[strings.ToUpper(value)]
+			if !ok {
+				return rule, fmt.Errorf("%q is not a weekday", value)
+			}
+
+			rule.WeekStart = weekday
+
+		default:
+			return rule, fmt.Errorf("recurrence rules with %s aren't supported", key)
+		}
+
+		if err != nil {
+			return rule, fmt.Errorf("reading %s of the recurrence rule: %w", key, err)
+		}
+	}
+
+	switch rule.Frequency {
+	case "DAILY", "WEEKLY", "MONTHLY":
+
+	case "YEARLY":
+		if len(rule.ByDay) > 0 && len(rule.ByMonth) == 0 {
+			return rule, fmt.Errorf("yearly recurrence rules with BYDAY but no BYMONTH aren't supported")
+		}
+
+	default:
+		return rule, fmt.Errorf("recurrence frequency %q isn't supported", rule.Frequency)
+	}
+
+	return rule, nil
+}
+
+// occurrences lists when the instances of a series starting at start begin, up
+// to until. The start is always the first of them.
+func (r icalRecurrence) occurrences(start time.Time, until time.Time) []time.Time {
+	if !r.Until.IsZero() && r.Until.Before(until) {
+		until = r.Until
+	}
+
+	starts := []time.Time{start}
+	hour, minute, second := start.Clock()
+
+	// Days are worked out in UTC so that adding them isn't thrown by daylight
+	// saving changes, then put back at the time of day of the start
+	year, month, day := start.Date()
+	first := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	for period := 0; r.Count == 0 || len(starts) < r.Count; period++ {
+		periodStart, days := r.period(first, period)
+
+		if periodStart.After(time.Date(until.Year(), until.Month(), until.Day(), 0, 0, 0, 0, time.UTC)) {
+			return starts
+		}
+
+		for _, day := range days {
+			occurrence := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, 0, start.Location())
+
+			if !occurrence.After(start) {
+				continue
+			}
+
+			if occurrence.After(until) {
+				return starts
+			}
+
+			starts = append(starts, occurrence)
+
+			if len(starts) == r.Count || len(starts) == maxRecurrences {
+				return starts
+			}
+		}
+	}
+
+	return starts
+}
+
+// period lists the days, in order, the rule picks in the nth day, week, month
+// or year of the series starting on first, along with when that period starts.
+func (r icalRecurrence) period(first time.Time, n int) (time.Time, []time.Time) {
+	var periodStart time.Time
+	var days []time.Time
+
+	switch r.Frequency {
+	case "DAILY":
+		periodStart = first.AddDate(0, 0, n*r.Interval)
+		days = []time.Time{periodStart}
+
+	case "WEEKLY":
+		// The week is the one the first day falls in
+		offset := (int(first.Weekday()) - int(r.WeekStart) + 7) % 7
+		periodStart = first.AddDate(0, 0, -offset+7*n*r.Interval)
+
+		if len(r.ByDay) == 0 {
+			days = []time.Time{periodStart.AddDate(0, 0, offset)}
+		} else {
+			for i := range 7 {
+				if day := periodStart.AddDate(0, 0, i); r.hasWeekday(day) {
+					days = append(days, day)
+				}
+			}
+		}
+
+		return periodStart, r.filter(days, false)
+
+	case "MONTHLY":
+		periodStart = time.Date(first.Year(), first.Month()+time.Month(n*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		days = r.monthDays(periodStart, first.Day())
+
+		return periodStart, r.filter(days, false)
+
+	case "YEARLY":
+		periodStart = time.Date(first.Year()+n*r.Interval, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{first.Month()}
+		}
+
+		for _, month := range slices.Sorted(slices.Values(months)) {
+			days = append(days, r.monthDays(time.Date(periodStart.Year(), month, 1, 0, 0, 0, 0, time.UTC), first.Day())...)
+		}
+
+		return periodStart, days
+	}
+
+	return periodStart, r.filter(days, true)
+}
+
+// monthDays lists the days of the month starting on firstOfMonth the rule
+// picks, the day of the first instance when it picks none in particular.
+// Months too short for that day are skipped.
+func (r icalRecurrence) monthDays(firstOfMonth time.Time, defaultDay int) []time.Time {
+	monthLength := daysInMonth(firstOfMonth)
+
+	var days []time.Time
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, n := range r.ByMonthDay {
+			if n < 0 {
+				n += monthLength + 1
+			}
+
+			if n >= 1 && n <= monthLength {
+				day := firstOfMonth.AddDate(0, 0, n-1)
+
+				if len(r.ByDay) == 0 || r.hasWeekday(day) {
+					days = append(days, day)
+				}
+			}
+		}
+
+	case len(r.ByDay) > 0:
+		for _, weekday := range r.ByDay {
+			var matching []time.Time
+			for i := range monthLength {
+				if day := firstOfMonth.AddDate(0, 0, i); day.Weekday() == weekday.Weekday {
+					matching = append(matching, day)
+				}
+			}
+
+			switch {
+			case weekday.Ordinal == 0:
+				days = append(days, matching...)
+
+			case weekday.Ordinal > 0 && weekday.Ordinal <= len(matching):
+				days = append(days, matching[weekday.Ordinal-1])
+
+			case weekday.Ordinal < 0 && -weekday.Ordinal <= len(matching):
+				days = append(days, matching[len(matching)+weekday.Ordinal])
+			}
+		}
+
+	case defaultDay <= monthLength:
+		days = append(days, firstOfMonth.AddDate(0, 0, defaultDay-1))
+	}
+
+	slices.SortFunc(days, time.Time.Compare)
+
+	return slices.CompactFunc(days, time.Time.Equal)
+}
+
+// filter keeps the days in the months the rule is limited to and, when
+// byWeekday, on the weekdays it's limited to.
+func (r icalRecurrence) filter(days []time.Time, byWeekday bool) []time.Time {
+	return slices.DeleteFunc(days, func(day time.Time) bool {
+		if len(r.ByMonth) > 0 && !slices.Contains(r.ByMonth, day.Month()) {
+			return true
+		}
+
+		if byWeekday && len(r.ByMonthDay) > 0 && !slices.ContainsFunc(r.ByMonthDay, func(n int) bool {
+			return n == day.Day() || n == day.Day()-daysInMonth(day)-1
+		}) {
+			return true
+		}
+
+		return byWeekday && !r.hasWeekday(day)
+	})
+}
+
+func daysInMonth(day time.Time) int {
+	return time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// hasWeekday tells whether the day is on one of the weekdays the rule is
+// limited to, any day when it isn't.
+func (r icalRecurrence) hasWeekday(day time.Time) bool {
+	return len(r.ByDay) == 0 || slices.ContainsFunc(r.ByDay, func(weekday icalWeekday) bool {
+		return weekday.Weekday == day.Weekday()
+	})
+}
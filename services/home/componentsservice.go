@@ -7,6 +7,7 @@ import (
 	"github.com/angelofallars/htmx-go"
 	"github.com/labstack/echo/v4"
 	"github.com/pleimann/camel-do/templates/components"
+	"github.com/pleimann/camel-do/utils"
 )
 
 type ComponentsService struct {
@@ -32,12 +33,12 @@ func (cs *ComponentsService) handleDatePickerCalendar(c echo.Context) error {
 	
 	month, err := strconv.Atoi(monthStr)
 	if err != nil {
-		month = int(time.Now().Month())
+		month = int(utils.Now().Month())
 	}
 	
 	year, err := strconv.Atoi(yearStr)
 	if err != nil {
-		year = time.Now().Year()
+		year = utils.Now().Year()
 	}
 	
 	calendar := components.DatePickerCalendar(year, time.Month(month))
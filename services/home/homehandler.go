@@ -7,11 +7,13 @@ import (
 
 	"github.com/a-h/templ"
 	"github.com/labstack/echo/v4"
+	"github.com/pleimann/camel-do/model"
 	"github.com/pleimann/camel-do/services/cal"
 	"github.com/pleimann/camel-do/services/project"
 	"github.com/pleimann/camel-do/services/task"
 	"github.com/pleimann/camel-do/templates"
 	"github.com/pleimann/camel-do/templates/pages"
+	"github.com/pleimann/camel-do/utils"
 )
 
 type HomeHandler struct {
@@ -36,12 +38,18 @@ func (h HomeHandler) ServeHTTP(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "render page method %s status path %s", c.Request().Method, c.Request().URL.Path)
 	}
 
-	todaysEvents, err := h.calendarService.GetTodaysEvents()
+	today := utils.Now()
+
+	// The week's events are read at once, today's are among them
+	startOfWeek, _ := model.WeekOf(today)
+	weekEvents, err := h.calendarService.GetEventsByDay(startOfWeek, 7)
 	if err != nil {
-		msg := fmt.Sprintf("get tasks for today %s", err.Error())
+		msg := fmt.Sprintf("get events for this week %s", err.Error())
 		return echo.NewHTTPError(http.StatusInternalServerError, msg)
 	}
 
+	todaysEvents := weekEvents[(int(today.Weekday())-int(time.Monday)+7)%7]
+
 	// Get backlog and tasks scheduled for today
 	backlogTasks, err := h.taskService.GetBacklogTasks()
 	if err != nil {
@@ -49,21 +57,22 @@ func (h HomeHandler) ServeHTTP(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, msg)
 	}
 
-	todaysTasks, err := h.taskService.GetTodaysTasks()
+	todaysTasks, err := h.taskService.GetTasksScheduledOnDate(today)
 	if err != nil {
 		msg := fmt.Sprintf("get tasks for today %s", err.Error())
 		return echo.NewHTTPError(http.StatusInternalServerError, msg)
 	}
 
-	projectIndex, err := h.projectService.GetProjects()
+	projectIndex, err := h.projectService.GetAllProjects()
 	if err != nil {
 		msg := fmt.Sprintf("get all projects %s", err.Error())
 		return echo.NewHTTPError(http.StatusInternalServerError, msg)
 	}
 
-	today := time.Now()
+	// Leaves out the tasks of archived projects
+	backlogTasks = model.TaskFilter{}.Apply(backlogTasks, projectIndex)
 
-	main := pages.Main(today, backlogTasks, todaysTasks, todaysEvents, projectIndex)
+	main := pages.Main(today, backlogTasks, todaysTasks, todaysEvents, weekEvents, projectIndex)
 
 	// Define template layout for index page.
 	indexTemplate := templates.Layout(
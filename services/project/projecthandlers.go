@@ -1,14 +1,24 @@
 package project
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"math"
 	"net/http"
-	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/angelofallars/htmx-go"
+	"github.com/guregu/null/v6/zero"
 	"github.com/labstack/echo/v4"
+	"github.com/oklog/ulid/v2"
+	bolt "go.etcd.io/bbolt"
 
 	"github.com/pleimann/camel-do/model"
+	"github.com/pleimann/camel-do/services/cal"
 	"github.com/pleimann/camel-do/templates/components"
 	"github.com/pleimann/camel-do/templates/pages"
 	"github.com/pleimann/camel-do/utils"
@@ -16,13 +26,45 @@ import (
 
 type ProjectHandler struct {
 	*echo.Group
-	projectService *ProjectService
+	projectService  *ProjectService
+	taskService     TaskService
+	calendarService CalendarService
+	settingsService SettingsService
 }
 
-func NewProjectHandler(group *echo.Group, projectService *ProjectService) *ProjectHandler {
+// TaskService interface to avoid circular dependencies
+type TaskService interface {
+	AddTask(task *model.Task) error
+	GetAllTasks() (*model.TaskList, error)
+	GetProjectTasks(projectID string) (*model.TaskList, error)
+	SearchTasks(query string) (*model.TaskList, error)
+	ReassignProjectTasks(tx *bolt.Tx, fromProjectID string, to *model.Project) error
+	GetTasksScheduledBetween(start time.Time, end time.Time) (*model.TaskList, error)
+}
+
+// CalendarService interface to avoid circular dependencies
+type CalendarService interface {
+	GetEventsBetween(start time.Time, end time.Time) (*model.EventList, error)
+}
+
+// SettingsService interface to avoid circular dependencies
+type SettingsService interface {
+	GetSettings() (*model.Settings, error)
+}
+
+func NewProjectHandler(
+	group *echo.Group,
+	projectService *ProjectService,
+	taskService TaskService,
+	calendarService CalendarService,
+	settingsService SettingsService,
+) *ProjectHandler {
 	projectHandler := &ProjectHandler{
-		Group:          group,
-		projectService: projectService,
+		Group:           group,
+		projectService:  projectService,
+		taskService:     taskService,
+		calendarService: calendarService,
+		settingsService: settingsService,
 	}
 
 	group.GET("/new", projectHandler.handleNewProject).Name = "new-project"
@@ -33,6 +75,22 @@ func NewProjectHandler(group *echo.Group, projectService *ProjectService) *Proje
 	group.DELETE("/:id", projectHandler.handleProjectDelete).Name = "delete-project"
 	group.PUT("/:id", projectHandler.handleProjectUpdate).Name = "update-project"
 
+	group.GET("/:id/tasks", projectHandler.handleProjectTasks).Name = "project-tasks"
+	group.POST("/:id/tasks", projectHandler.handleProjectQuickAdd).Name = "project-quick-add"
+	group.POST("/:id/import", projectHandler.handleProjectImport).Name = "project-import"
+
+	group.GET("/archived", projectHandler.handleListArchivedProjects).Name = "list-archived-projects"
+	group.GET("/archived/:id", projectHandler.handleArchivedProject).Name = "archived-project"
+	group.GET("/archived/:id/tasks", projectHandler.handleArchivedProjectTasks).Name = "archived-project-tasks"
+	group.PUT("/:id/archive", projectHandler.handleProjectArchive).Name = "archive-project"
+	group.DELETE("/:id/archive", projectHandler.handleProjectUnarchive).Name = "unarchive-project"
+	group.PUT("/:id/pin", projectHandler.handleProjectPin).Name = "pin-project"
+	group.DELETE("/:id/pin", projectHandler.handleProjectUnpin).Name = "unpin-project"
+	group.PUT("/order", projectHandler.handleProjectMove).Name = "move-project"
+
+	group.POST("/icons", projectHandler.handleIconUpload).Name = "upload-icon"
+	group.GET("/icons/:iconId", projectHandler.handleGetIcon).Name = "project-icon"
+
 	return projectHandler
 }
 
@@ -48,7 +106,17 @@ func extractTaskId(c echo.Context) string {
 }
 
 func (h *ProjectHandler) handleNewProject(c echo.Context) error {
-	newProjectDialogTemplate := pages.ProjectDialog(nil)
+	projectsIndex, err := h.projectService.GetProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	icons, err := h.projectService.GetIcons()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting icons", err)
+	}
+
+	newProjectDialogTemplate := pages.ProjectDialog(nil, projectsIndex, icons)
 
 	dialogTemplate := components.Dialog(newProjectDialogTemplate)
 
@@ -73,7 +141,17 @@ func (h *ProjectHandler) handleEditProject(c echo.Context) error {
 		}
 
 	} else {
-		editProjectDialogTemplate := pages.ProjectDialog(project)
+		projectsIndex, err := h.projectService.GetProjects()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+		}
+
+		icons, err := h.projectService.GetIcons()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting icons", err)
+		}
+
+		editProjectDialogTemplate := pages.ProjectDialog(project, projectsIndex, icons)
 
 		dialogTemplate := components.Dialog(editProjectDialogTemplate)
 
@@ -86,19 +164,40 @@ func (h *ProjectHandler) handleEditProject(c echo.Context) error {
 }
 
 func (h *ProjectHandler) handleListProjects(c echo.Context) error {
+	return h.renderProjectList(c, htmx.NewResponse())
+}
+
+// renderProjectList renders the project tree along with the number of open
+// tasks in each project and the projects nested in it.
+func (h *ProjectHandler) renderProjectList(c echo.Context, response htmx.Response) error {
 	projectsIndex, err := h.projectService.GetProjects()
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
 	}
 
-	projects := slices.Collect(projectsIndex.Values())
+	tasks, err := h.taskService.GetAllTasks()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting tasks", err)
+	}
+
+	counts := map[string]int{}
+	for task := range tasks.All() {
+		if task.ProjectID.Valid && !task.Status.IsClosed() {
+			counts[task.ProjectID.String]++
+		}
+	}
+
+	budgets, err := h.weeklyBudgets(utils.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting budgets", err)
+	}
 
-	listProjectsDialogTemplate := pages.ProjectList(projects)
+	listProjectsDialogTemplate := pages.ProjectList(projectsIndex, projectsIndex.RollUp(counts), budgets)
 
 	dialogTemplate := components.Dialog(listProjectsDialogTemplate)
 
-	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, dialogTemplate); err != nil {
+	if err := response.RenderTempl(c.Request().Context(), c.Response().Writer, dialogTemplate); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
 	}
 
@@ -108,14 +207,41 @@ func (h *ProjectHandler) handleListProjects(c echo.Context) error {
 func (h *ProjectHandler) handleProjectCreate(c echo.Context) error {
 	project := model.Project{}
 
-	if err := c.Bind(project); err != nil {
+	if err := c.Bind(&project); err != nil {
 		return echo.NewHTTPError(http.StatusUnprocessableEntity, "decoding form data", err)
 	}
 
+	customFields, err := parseCustomFields(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	project.CustomFields = customFields
+
+	milestones, err := parseMilestones(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	project.Milestones = milestones
+
+	if err := h.checkAppearance(&project); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	if project.WeeklyBudget, err = parseWeeklyBudget(c); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
 	slog.Debug("ProjectHandler.handleProjectCreate", "project", project)
 
 	if err := h.projectService.AddProject(project); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "adding project", err)
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, "parent project not found", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "adding project", err)
+		}
 	}
 
 	return htmx.NewResponse().
@@ -128,9 +254,13 @@ func (h *ProjectHandler) handleProjectDelete(c echo.Context) error {
 
 	slog.Debug("ProjectHandler.handleProjectDelete", "projectId", id)
 
-	// TODO: remove projectID from linked tasks
+	// Like its sub-projects the project's tasks move up to its parent, if any,
+	// in the same transaction so they're never left in a project that's gone
+	err := h.projectService.DeleteProject(id, func(tx *bolt.Tx, deleted *model.Project, parent *model.Project) error {
+		return h.taskService.ReassignProjectTasks(tx, id, parent)
+	})
 
-	if err := h.projectService.DeleteProject(id); err != nil {
+	if err != nil {
 		if utils.IsNotFoundError(err) {
 			return echo.NewHTTPError(http.StatusNotFound, "deleting project", err)
 
@@ -139,7 +269,8 @@ func (h *ProjectHandler) handleProjectDelete(c echo.Context) error {
 		}
 	}
 
-	return nil
+	// Sub-projects moved so the whole tree is rendered again
+	return h.renderProjectList(c, htmx.NewResponse().Retarget("#dialog").Reswap(htmx.SwapInnerHTML))
 }
 
 func (h *ProjectHandler) handleProjectUpdate(c echo.Context) error {
@@ -149,16 +280,598 @@ func (h *ProjectHandler) handleProjectUpdate(c echo.Context) error {
 
 	var project model.Project
 	if err := c.Bind(&project); err != nil {
-		echo.NewHTTPError(http.StatusUnprocessableEntity, "decoding form data", err)
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "decoding form data", err)
+	}
+
+	customFields, err := parseCustomFields(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	project.CustomFields = customFields
+
+	milestones, err := parseMilestones(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	project.Milestones = milestones
+
+	if err := h.checkAppearance(&project); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	if project.WeeklyBudget, err = parseWeeklyBudget(c); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
 	}
 
 	c.Logger().Debug("ProjectHandler.handleProjectUpdate", "project", project)
 
 	if err := h.projectService.UpdateProject(id, project); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "adding project", err)
+		if errors.Is(err, model.ErrProjectCycle) {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, model.ErrProjectCycle.Error())
+
+		} else if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, "parent project not found", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "adding project", err)
+		}
 	}
 
 	return htmx.NewResponse().
 		Refresh(true).
 		Write(c.Response().Writer)
 }
+
+func (h *ProjectHandler) handleProjectArchive(c echo.Context) error {
+	return h.archiveProject(c, true)
+}
+
+func (h *ProjectHandler) handleProjectUnarchive(c echo.Context) error {
+	return h.archiveProject(c, false)
+}
+
+// archiveProject archives or restores the project. Either way it leaves the
+// list it was shown in.
+func (h *ProjectHandler) archiveProject(c echo.Context, archived bool) error {
+	id := extractTaskId(c)
+
+	slog.Debug("ProjectHandler.archiveProject", "projectId", id, "archived", archived)
+
+	if err := h.projectService.ArchiveProject(id, archived); err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "archiving project", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "archiving project", err)
+		}
+	}
+
+	// Sub-projects were archived or restored too so the whole list is rendered again
+	response := htmx.NewResponse().Retarget("#dialog").Reswap(htmx.SwapInnerHTML)
+	if archived {
+		return h.renderProjectList(c, response)
+	}
+
+	return h.renderArchivedProjectList(c, response)
+}
+
+func (h *ProjectHandler) handleProjectPin(c echo.Context) error {
+	return h.pinProject(c, true)
+}
+
+func (h *ProjectHandler) handleProjectUnpin(c echo.Context) error {
+	return h.pinProject(c, false)
+}
+
+func (h *ProjectHandler) pinProject(c echo.Context, pinned bool) error {
+	id := extractTaskId(c)
+
+	slog.Debug("ProjectHandler.pinProject", "projectId", id, "pinned", pinned)
+
+	if err := h.projectService.PinProject(id, pinned); err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "pinning project", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "pinning project", err)
+		}
+	}
+
+	return h.renderProjectList(c, htmx.NewResponse().Retarget("#dialog").Reswap(htmx.SwapInnerHTML))
+}
+
+// handleProjectMove stores the order of the project list after a project was
+// dragged onto another one.
+func (h *ProjectHandler) handleProjectMove(c echo.Context) error {
+	id := c.FormValue("projectId")
+	targetID := c.FormValue("targetId")
+
+	slog.Debug("ProjectHandler.handleProjectMove", "projectId", id, "targetId", targetID)
+
+	if err := h.projectService.MoveProject(id, targetID); err != nil {
+		if errors.Is(err, model.ErrProjectCycle) || errors.Is(err, model.ErrProjectArchived) {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+
+		} else if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "moving project", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "moving project", err)
+		}
+	}
+
+	return h.renderProjectList(c, htmx.NewResponse().Retarget("#dialog").Reswap(htmx.SwapInnerHTML))
+}
+
+func (h *ProjectHandler) handleListArchivedProjects(c echo.Context) error {
+	return h.renderArchivedProjectList(c, htmx.NewResponse())
+}
+
+func (h *ProjectHandler) renderArchivedProjectList(c echo.Context, response htmx.Response) error {
+	projects, err := h.projectService.GetArchivedProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting archived projects", err)
+	}
+
+	dialogTemplate := components.Dialog(pages.ArchivedProjectList(projects))
+
+	if err := response.RenderTempl(c.Request().Context(), c.Response().Writer, dialogTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handleArchivedProject opens the browsable history of an archived project.
+func (h *ProjectHandler) handleArchivedProject(c echo.Context) error {
+	id := extractTaskId(c)
+
+	project, err := h.projectService.GetProject(id)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "getting project", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting project", err)
+		}
+	}
+
+	tasks, err := h.taskService.GetProjectTasks(id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting project tasks", err)
+	}
+
+	projectsIndex, err := h.projectService.GetAllProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	dialogTemplate := components.Dialog(pages.ArchivedProject(project, tasks, projectsIndex))
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, dialogTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handleArchivedProjectTasks searches the tasks of an archived project.
+func (h *ProjectHandler) handleArchivedProjectTasks(c echo.Context) error {
+	id := extractTaskId(c)
+	query := c.QueryParam("q")
+
+	var tasks *model.TaskList
+	var err error
+	if strings.TrimSpace(query) == "" {
+		tasks, err = h.taskService.GetProjectTasks(id)
+
+	} else if tasks, err = h.taskService.SearchTasks(query); err == nil {
+		tasks = tasks.Filter(func(task model.Task) bool {
+			return task.ProjectID.String == id
+		})
+	}
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "searching project tasks", err)
+	}
+
+	projectsIndex, err := h.projectService.GetAllProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	resultsTemplate := pages.SearchResults(tasks, projectsIndex)
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, resultsTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handleProjectTasks opens the project's page with its tasks and progress.
+func (h *ProjectHandler) handleProjectTasks(c echo.Context) error {
+	id := extractTaskId(c)
+
+	slog.Debug("ProjectHandler.handleProjectTasks", "projectId", id)
+
+	project, err := h.projectService.GetProject(id)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "getting project", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting project", err)
+		}
+	}
+
+	return h.renderProjectDetail(c, htmx.NewResponse(), project)
+}
+
+func (h *ProjectHandler) renderProjectDetail(c echo.Context, response htmx.Response, project *model.Project) error {
+	tasks, err := h.taskService.GetProjectTasks(project.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting project tasks", err)
+	}
+
+	projectsIndex, err := h.projectService.GetAllProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	settings, err := h.settingsService.GetSettings()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting settings", err)
+	}
+
+	// Milestones are at risk when their work doesn't fit in the working hours
+	// left, the same ones tasks are scheduled in
+	summary := model.SummarizeProject(project, tasks, utils.Now(), settings.WorkingHours.OrDefault())
+
+	dialogTemplate := components.Dialog(pages.ProjectDetail(project, summary, projectsIndex))
+
+	if err := response.RenderTempl(c.Request().Context(), c.Response().Writer, dialogTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handleProjectImport adds the events of an uploaded iCalendar file, like a
+// conference schedule, to the project as tasks scheduled when they take
+// place, then shows the project's page again.
+func (h *ProjectHandler) handleProjectImport(c echo.Context) error {
+	id := extractTaskId(c)
+
+	slog.Debug("ProjectHandler.handleProjectImport", "projectId", id)
+
+	project, err := h.projectService.GetProject(id)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "getting project", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting project", err)
+		}
+	}
+
+	// Required custom fields can only be filled in from the task dialog
+	if err := project.ValidateCustomValues(nil); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "reading calendar file", err)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "reading calendar file", err)
+	}
+
+	defer file.Close()
+
+	// Tasks don't repeat, so a recurring event becomes a single task rather
+	// than one for every meeting of the series
+	_, events, err := cal.ReadICS(file, time.Time{})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("%s isn't a calendar file: %s", fileHeader.Filename, err))
+	}
+
+	for _, event := range events {
+		task := &model.Task{
+			Title:       event.Title,
+			Description: event.Description,
+			StartTime:   event.StartTime,
+			Duration:    event.Duration,
+			AllDay:      event.AllDay,
+			EndTime:     event.EndTime,
+			ProjectID:   zero.StringFrom(project.ID),
+		}
+
+		project.Defaults.Apply(task, utils.Now())
+
+		if err := h.taskService.AddTask(task); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "adding task", err)
+		}
+	}
+
+	// The timeline reloads to show the newly scheduled tasks
+	response := htmx.NewResponse()
+	if len(events) > 0 {
+		response = response.AddTrigger(htmx.Trigger("calendar-changed"))
+	}
+
+	return h.renderProjectDetail(c, response, project)
+}
+
+// handleProjectQuickAdd adds a task to the project's backlog from just a title.
+func (h *ProjectHandler) handleProjectQuickAdd(c echo.Context) error {
+	id := extractTaskId(c)
+
+	title := strings.TrimSpace(c.FormValue("title"))
+	if title == "" {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "a task needs a title")
+	}
+
+	slog.Debug("ProjectHandler.handleProjectQuickAdd", "projectId", id, "title", title)
+
+	project, err := h.projectService.GetProject(id)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "getting project", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting project", err)
+		}
+	}
+
+	// Required custom fields can only be filled in from the task dialog
+	if err := project.ValidateCustomValues(nil); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	task := &model.Task{
+		Title:     zero.StringFrom(title),
+		ProjectID: zero.StringFrom(project.ID),
+	}
+
+	project.Defaults.Apply(task, utils.Now())
+
+	if err := h.taskService.AddTask(task); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "adding task", err)
+	}
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, pages.ProjectTaskItem(*task, project)); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// weeklyBudgets works out how the budgeted projects are tracking this week.
+// Without the calendar only scheduled tasks are counted.
+func (h *ProjectHandler) weeklyBudgets(now time.Time) (map[string]model.BudgetUsage, error) {
+	start, end := model.WeekOf(now)
+
+	projectsIndex, err := h.projectService.GetAllProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := h.taskService.GetTasksScheduledBetween(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := h.calendarService.GetEventsBetween(start, end)
+	if err != nil {
+		slog.Warn("counting budgets without calendar events", "error", err)
+		events = model.NewEventList()
+	}
+
+	return model.BudgetUsages(projectsIndex, tasks, events), nil
+}
+
+// parseWeeklyBudget reads the weekly budget the project dialog takes in hours.
+func parseWeeklyBudget(c echo.Context) (zero.Int32, error) {
+	hoursStr := strings.TrimSpace(c.FormValue("weeklyBudgetHours"))
+	if hoursStr == "" {
+		return zero.Int32{}, nil
+	}
+
+	hours, err := strconv.ParseFloat(hoursStr, 64)
+	if err != nil || hours < 0 {
+		return zero.Int32{}, fmt.Errorf("weekly budget %q isn't a number of hours", hoursStr)
+	}
+
+	return zero.Int32From(int32(math.Round(hours * 60))), nil
+}
+
+// checkAppearance normalizes the project's custom color and makes sure its
+// uploaded icon exists.
+func (h *ProjectHandler) checkAppearance(project *model.Project) error {
+	if project.HexColor != "" {
+		hexColor, err := model.ParseHexColor(string(project.HexColor))
+		if err != nil {
+			return err
+		}
+
+		project.HexColor = hexColor
+	}
+
+	if project.CustomIconID.Valid {
+		if _, err := h.projectService.GetIcon(project.CustomIconID.String); err != nil {
+			return fmt.Errorf("icon %s not found", project.CustomIconID.String)
+		}
+	}
+
+	return nil
+}
+
+// handleIconUpload stores an uploaded SVG icon after stripping anything which
+// could run or load content, and renders it as a choice in the icon picker.
+func (h *ProjectHandler) handleIconUpload(c echo.Context) error {
+	fileHeader, err := c.FormFile("iconFile")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "reading uploaded icon", err)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "reading uploaded icon", err)
+	}
+	defer file.Close()
+
+	// One byte over the limit is enough to reject it
+	data, err := io.ReadAll(io.LimitReader(file, model.MaxCustomIconSize+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "reading uploaded icon", err)
+	}
+
+	svg, err := model.SanitizeSVG(data)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	icon := &model.CustomIcon{
+		Name: fileHeader.Filename,
+		SVG:  svg,
+	}
+
+	slog.Debug("ProjectHandler.handleIconUpload", "name", icon.Name, "size", len(svg))
+
+	if err := h.projectService.AddIcon(icon); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "storing icon", err)
+	}
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, pages.CustomIconChoice(*icon)); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+func (h *ProjectHandler) handleGetIcon(c echo.Context) error {
+	id := c.Param("iconId")
+
+	icon, err := h.projectService.GetIcon(id)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "getting icon", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting icon", err)
+		}
+	}
+
+	// Icons are sanitized on upload; the policy keeps them inert even if one slipped through
+	header := c.Response().Header()
+	header.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Cache-Control", "public, max-age=31536000, immutable")
+
+	return c.Blob(http.StatusOK, "image/svg+xml", icon.SVG)
+}
+
+// parseCustomFields reads the custom field definitions submitted by the
+// project dialog. Each field is a row of same-indexed form values.
+func parseCustomFields(c echo.Context) ([]model.CustomField, error) {
+	form, err := c.FormParams()
+	if err != nil {
+		return nil, err
+	}
+
+	ids, names, types := form["fieldId"], form["fieldName"], form["fieldType"]
+	options, required := form["fieldOptions"], form["fieldRequired"]
+
+	if len(ids) != len(names) || len(names) != len(types) ||
+		len(types) != len(options) || len(options) != len(required) {
+		return nil, fmt.Errorf("custom field rows are incomplete")
+	}
+
+	customFields := make([]model.CustomField, 0, len(names))
+	for i, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		fieldType, err := model.ParseFieldTypeString(types[i])
+		if err != nil {
+			return nil, fmt.Errorf("custom field %s: %w", name, err)
+		}
+
+		field := model.CustomField{
+			ID:       ids[i],
+			Name:     name,
+			Type:     fieldType,
+			Required: required[i] == "true",
+		}
+
+		if field.ID == "" {
+			field.ID = ulid.Make().String()
+		}
+
+		if fieldType == model.SelectField {
+			for option := range strings.SplitSeq(options[i], ",") {
+				if option = strings.TrimSpace(option); option != "" {
+					field.Options = append(field.Options, option)
+				}
+			}
+
+			if len(field.Options) == 0 {
+				return nil, fmt.Errorf("custom field %s needs at least one option", name)
+			}
+		}
+
+		customFields = append(customFields, field)
+	}
+
+	return customFields, nil
+}
+
+// parseMilestones reads the milestones submitted by the project dialog, one
+// row of same-indexed form values each.
+func parseMilestones(c echo.Context) ([]model.Milestone, error) {
+	form, err := c.FormParams()
+	if err != nil {
+		return nil, err
+	}
+
+	ids, names, dates := form["milestoneId"], form["milestoneName"], form["milestoneDate"]
+
+	if len(ids) != len(names) || len(names) != len(dates) {
+		return nil, fmt.Errorf("milestone rows are incomplete")
+	}
+
+	milestones := make([]model.Milestone, 0, len(names))
+	for i, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		targetDate, err := utils.ParseDate(model.MilestoneDateFormat, dates[i])
+		if err != nil {
+			return nil, fmt.Errorf("milestone %s needs a target date", name)
+		}
+
+		milestone := model.Milestone{
+			ID:         ids[i],
+			Name:       name,
+			TargetDate: targetDate,
+		}
+
+		if milestone.ID == "" {
+			milestone.ID = ulid.Make().String()
+		}
+
+		milestones = append(milestones, milestone)
+	}
+
+	return milestones, nil
+}
@@ -4,7 +4,10 @@ import (
 	"encoding/gob"
 	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 
+	"github.com/guregu/null/v6/zero"
 	"github.com/oklog/ulid/v2"
 	"github.com/pleimann/camel-do/model"
 	"github.com/pleimann/camel-do/utils"
@@ -58,34 +61,101 @@ func (s *ProjectService) GetProject(id string) (*model.Project, error) {
 	return &project, nil
 }
 
+// GetProjects returns the projects which aren't archived.
 func (s *ProjectService) GetProjects() (*model.ProjectIndex, error) {
 	slog.Debug("ProjectService.GetProjects")
 
-	var projectsIndex = model.NewProjectIndex()
+	projectsIndex, err := s.getProjects(func(project model.Project) bool {
+		return !project.Archived
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("fetching all projects %w", err)
+	}
+
+	return projectsIndex, nil
+}
+
+// GetAllProjects returns the archived projects too, for showing tasks which
+// may belong to one.
+func (s *ProjectService) GetAllProjects() (*model.ProjectIndex, error) {
+	slog.Debug("ProjectService.GetAllProjects")
+
+	projectsIndex, err := s.getProjects(func(project model.Project) bool {
+		return true
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("fetching all projects %w", err)
+	}
+
+	return projectsIndex, nil
+}
+
+// GetArchivedProjects returns the archived projects, most recently archived first.
+func (s *ProjectService) GetArchivedProjects() ([]model.Project, error) {
+	slog.Debug("ProjectService.GetArchivedProjects")
+
+	var projects []model.Project
 	err := s.db.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte("projects"))
 
-		err := bucket.ForEach(func(k, projectBytes []byte) error {
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, projectBytes []byte) error {
 			project := model.Project{}
 
 			if err := project.Unmarshal(projectBytes); err != nil {
 				return err
 			}
 
-			projectsIndex.Add(project)
+			if project.Archived {
+				projects = append(projects, project)
+			}
 
 			return nil
 		})
+	})
 
-		if err != nil {
-			return err
+	if err != nil {
+		return nil, fmt.Errorf("fetching archived projects %w", err)
+	}
+
+	slices.SortFunc(projects, func(a, b model.Project) int {
+		return b.ArchivedAt.Time.Compare(a.ArchivedAt.Time)
+	})
+
+	return projects, nil
+}
+
+func (s *ProjectService) getProjects(keep func(model.Project) bool) (*model.ProjectIndex, error) {
+	var projectsIndex = model.NewProjectIndex()
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("projects"))
+
+		if bucket == nil {
+			return nil
 		}
 
-		return nil
+		return bucket.ForEach(func(k, projectBytes []byte) error {
+			project := model.Project{}
+
+			if err := project.Unmarshal(projectBytes); err != nil {
+				return err
+			}
+
+			if keep(project) {
+				projectsIndex.Add(project)
+			}
+
+			return nil
+		})
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("fetching all projects %w", err)
+		return nil, err
 	}
 
 	return projectsIndex, nil
@@ -96,63 +166,265 @@ func (s *ProjectService) AddProject(project model.Project) error {
 
 	slog.Debug("ProjectService.AddProject", "project", project)
 
-	s.db.Update(func(tx *bolt.Tx) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
 		bucket, err := tx.CreateBucketIfNotExists([]byte("projects"))
 
 		if err != nil {
 			return err
 		}
 
-		projectBytes, err := project.Marshal()
+		if project.ParentID.Valid && bucket.Get([]byte(project.ParentID.String)) == nil {
+			return utils.NewNotFoundError("project", project.ParentID.String)
+		}
+
+		projectsIndex, err := readProjects(bucket)
 		if err != nil {
 			return err
 		}
 
-		bucket.Put([]byte(project.ID), projectBytes)
+		// New projects go to the end of the list
+		for _, existing := range projectsIndex.All() {
+			project.Rank = max(project.Rank, existing.Rank+1)
+		}
 
-		return nil
+		return putProject(bucket, project)
 	})
 
+	if err != nil {
+		return fmt.Errorf("ProjectService.AddProject (%s): %w", project.Name, err)
+	}
+
 	return nil
 }
 
 func (s *ProjectService) UpdateProject(id string, project model.Project) error {
 	slog.Debug("ProjectService.UpdateProject", "project", project)
 
-	s.db.Update(func(tx *bolt.Tx) error {
+	project.ID = id
+	project.UpdatedAt = time.Now()
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
 		bucket, err := tx.CreateBucketIfNotExists([]byte("projects"))
 
 		if err != nil {
 			return err
 		}
 
-		projectBytes, err := project.Marshal()
+		if existingBytes := bucket.Get([]byte(id)); existingBytes != nil {
+			existing := model.Project{}
+			if err := existing.Unmarshal(existingBytes); err != nil {
+				return err
+			}
+
+			project.CreatedAt = existing.CreatedAt
+			project.Archived = existing.Archived
+			project.ArchivedAt = existing.ArchivedAt
+			project.Rank = existing.Rank
+			project.Pinned = existing.Pinned
+		}
+
+		if project.ParentID.Valid {
+			projectsIndex, err := readProjects(bucket)
+			if err != nil {
+				return err
+			}
+
+			// Moving a project under one of its sub-projects would detach the whole branch
+			if project.ParentID.String == id || projectsIndex.IsDescendant(project.ParentID.String, id) {
+				return model.ErrProjectCycle
+			}
+
+			if bucket.Get([]byte(project.ParentID.String)) == nil {
+				return utils.NewNotFoundError("project", project.ParentID.String)
+			}
+		}
+
+		return putProject(bucket, project)
+	})
+
+	if err != nil {
+		return fmt.Errorf("ProjectService.UpdateProject (%s): %w", id, err)
+	}
+
+	return nil
+}
+
+// ArchiveProject archives or restores the project along with its sub-projects,
+// keeping their tasks. Sub-projects archived on their own beforehand stay
+// archived when the project is restored.
+func (s *ProjectService) ArchiveProject(id string, archived bool) error {
+	slog.Debug("ProjectService.ArchiveProject", "id", id, "archived", archived)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("projects"))
+
+		if bucket == nil || bucket.Get([]byte(id)) == nil {
+			return utils.NewNotFoundError("project", id)
+		}
+
+		projectsIndex, err := readProjects(bucket)
 		if err != nil {
 			return err
 		}
 
-		bucket.Put([]byte(project.ID), projectBytes)
+		now := time.Now()
+		archivedAt := projectsIndex.Get(id).ArchivedAt
+
+		for _, projectID := range append(projectsIndex.Descendants(id), id) {
+			project := *projectsIndex.Get(projectID)
+
+			// Sub-projects archived beforehand keep when they were, and only
+			// those archived along with the project, at its time, are restored
+			if projectID != id {
+				if archived && project.Archived {
+					continue
+				}
+
+				if !archived && !project.ArchivedAt.Time.Equal(archivedAt.Time) {
+					continue
+				}
+			}
+
+			project.Archived = archived
+			project.UpdatedAt = now
+
+			if archived {
+				project.ArchivedAt = zero.TimeFrom(now)
+
+			} else {
+				project.ArchivedAt = zero.Time{}
+			}
+
+			if err := putProject(bucket, project); err != nil {
+				return err
+			}
+		}
 
 		return nil
 	})
 
+	if err != nil {
+		return fmt.Errorf("ProjectService.ArchiveProject (%s): %w", id, err)
+	}
+
 	return nil
 }
 
-func (s *ProjectService) DeleteProject(id string) error {
-	slog.Debug("ProjectService.DeleteProject", "id", id)
+// MoveProject drags the project next to the target project, nesting it in the
+// target's parent, and stores the new order of the siblings.
+func (s *ProjectService) MoveProject(id string, targetID string) error {
+	slog.Debug("ProjectService.MoveProject", "id", id, "targetId", targetID)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("projects"))
+
+		for _, projectID := range []string{id, targetID} {
+			if bucket == nil || bucket.Get([]byte(projectID)) == nil {
+				return utils.NewNotFoundError("project", projectID)
+			}
+		}
+
+		projectsIndex, err := readProjects(bucket)
+		if err != nil {
+			return err
+		}
+
+		siblings, err := projectsIndex.Move(id, targetID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		for _, project := range siblings {
+			project.UpdatedAt = now
+
+			if err := putProject(bucket, project); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("ProjectService.MoveProject (%s): %w", id, err)
+	}
+
+	return nil
+}
+
+// PinProject marks the project as a favorite or not.
+func (s *ProjectService) PinProject(id string, pinned bool) error {
+	slog.Debug("ProjectService.PinProject", "id", id, "pinned", pinned)
 
 	err := s.db.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte("projects"))
 
+		if bucket == nil {
+			return utils.NewNotFoundError("project", id)
+		}
+
 		projectBytes := bucket.Get([]byte(id))
 		if projectBytes == nil {
 			return utils.NewNotFoundError("project", id)
 		}
 
-		bucket.Delete([]byte(id))
+		project := model.Project{}
+		if err := project.Unmarshal(projectBytes); err != nil {
+			return err
+		}
+
+		project.Pinned = pinned
+		project.UpdatedAt = time.Now()
 
-		return nil
+		return putProject(bucket, project)
+	})
+
+	if err != nil {
+		return fmt.Errorf("ProjectService.PinProject (%s): %w", id, err)
+	}
+
+	return nil
+}
+
+// DeleteProject removes the project. Its sub-projects move up to its parent.
+// Whatever else goes with the project is done alongside in the same
+// transaction, the project is kept when it fails. The parent given alongside
+// has no ID when the project was at the top level.
+func (s *ProjectService) DeleteProject(id string, alongside func(tx *bolt.Tx, deleted *model.Project, parent *model.Project) error) error {
+	slog.Debug("ProjectService.DeleteProject", "id", id)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("projects"))
+
+		if bucket == nil || bucket.Get([]byte(id)) == nil {
+			return utils.NewNotFoundError("project", id)
+		}
+
+		projectsIndex, err := readProjects(bucket)
+		if err != nil {
+			return err
+		}
+
+		deleted := projectsIndex.Get(id)
+
+		if err := alongside(tx, deleted, projectsIndex.Get(deleted.ParentID.String)); err != nil {
+			return err
+		}
+
+		for _, project := range projectsIndex.All() {
+			if project.ParentID.String == id {
+				project.ParentID = deleted.ParentID
+				project.UpdatedAt = time.Now()
+
+				if err := putProject(bucket, project); err != nil {
+					return err
+				}
+			}
+		}
+
+		return bucket.Delete([]byte(id))
 	})
 
 	if err != nil {
@@ -161,3 +433,115 @@ func (s *ProjectService) DeleteProject(id string) error {
 
 	return nil
 }
+
+// AddIcon stores an uploaded icon, which has to be sanitized already.
+func (s *ProjectService) AddIcon(icon *model.CustomIcon) error {
+	icon.ID = ulid.Make().String()
+	icon.CreatedAt = time.Now()
+
+	slog.Debug("ProjectService.AddIcon", "id", icon.ID, "name", icon.Name)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("icons"))
+		if err != nil {
+			return err
+		}
+
+		iconBytes, err := icon.Marshal()
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(icon.ID), iconBytes)
+	})
+
+	if err != nil {
+		return fmt.Errorf("ProjectService.AddIcon (%s): %w", icon.Name, err)
+	}
+
+	return nil
+}
+
+func (s *ProjectService) GetIcon(id string) (*model.CustomIcon, error) {
+	icon := &model.CustomIcon{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("icons"))
+
+		if bucket == nil {
+			return utils.NewNotFoundError("icon", id)
+		}
+
+		iconBytes := bucket.Get([]byte(id))
+		if iconBytes == nil {
+			return utils.NewNotFoundError("icon", id)
+		}
+
+		return icon.Unmarshal(iconBytes)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("ProjectService.GetIcon (%s): %w", id, err)
+	}
+
+	return icon, nil
+}
+
+// GetIcons returns every uploaded icon, oldest first.
+func (s *ProjectService) GetIcons() ([]model.CustomIcon, error) {
+	var icons []model.CustomIcon
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("icons"))
+
+		if bucket == nil {
+			return nil
+		}
+
+		// ULIDs sort by creation time
+		return bucket.ForEach(func(k, iconBytes []byte) error {
+			icon := model.CustomIcon{}
+
+			if err := icon.Unmarshal(iconBytes); err != nil {
+				return err
+			}
+
+			icons = append(icons, icon)
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("ProjectService.GetIcons: %w", err)
+	}
+
+	return icons, nil
+}
+
+func readProjects(bucket *bolt.Bucket) (*model.ProjectIndex, error) {
+	projectsIndex := model.NewProjectIndex()
+
+	err := bucket.ForEach(func(k, projectBytes []byte) error {
+		project := model.Project{}
+
+		if err := project.Unmarshal(projectBytes); err != nil {
+			return err
+		}
+
+		projectsIndex.Add(project)
+
+		return nil
+	})
+
+	return projectsIndex, err
+}
+
+func putProject(bucket *bolt.Bucket, project model.Project) error {
+	projectBytes, err := project.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put([]byte(project.ID), projectBytes)
+}
@@ -0,0 +1,71 @@
+package project
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/guregu/null/v6/zero"
+	"github.com/pleimann/camel-do/model"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestArchiveProjectRestoresOnlyWhatWasArchivedWithIt(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	projectService, err := NewProjectService(&ProjectServiceConfig{}, db)
+	if err != nil {
+		t.Fatalf("NewProjectService() error = %v", err)
+	}
+
+	idOf := map[string]string{}
+	add := func(name string, parent string) {
+		project := model.Project{Name: name, ParentID: zero.NewString(idOf[parent], parent != "")}
+
+		if err := projectService.AddProject(project); err != nil {
+			t.Fatalf("AddProject(%s) error = %v", name, err)
+		}
+
+		projects, err := projectService.GetAllProjects()
+		if err != nil {
+			t.Fatalf("GetAllProjects() error = %v", err)
+		}
+
+		for id, project := range projects.All() {
+			if project.Name == name {
+				idOf[name] = id
+			}
+		}
+	}
+
+	add("House", "")
+	add("Kitchen", "House")
+	add("Garden", "House")
+
+	// The garden was given up on before the whole house was
+	for _, name := range []string{"Garden", "House"} {
+		if err := projectService.ArchiveProject(idOf[name], true); err != nil {
+			t.Fatalf("ArchiveProject(%s) error = %v", name, err)
+		}
+	}
+
+	if err := projectService.ArchiveProject(idOf["House"], false); err != nil {
+		t.Fatalf("ArchiveProject(House) error = %v", err)
+	}
+
+	projects, err := projectService.GetAllProjects()
+	if err != nil {
+		t.Fatalf("GetAllProjects() error = %v", err)
+	}
+
+	want := map[string]bool{"House": false, "Kitchen": false, "Garden": true}
+	for name, archived := range want {
+		if got := projects.Get(idOf[name]).Archived; got != archived {
+			t.Errorf("%s archived = %v, want %v", name, got, archived)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package settings
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/angelofallars/htmx-go"
+	"github.com/labstack/echo/v4"
+
+	"github.com/pleimann/camel-do/model"
+	"github.com/pleimann/camel-do/templates/pages"
+	"github.com/pleimann/camel-do/utils"
+)
+
+type SettingsHandler struct {
+	*echo.Group
+	settingsService *SettingsService
+	projectService  ProjectService
+}
+
+// ProjectService interface to avoid circular dependencies
+type ProjectService interface {
+	GetAllProjects() (*model.ProjectIndex, error)
+}
+
+func NewSettingsHandler(group *echo.Group, settingsService *SettingsService, projectService ProjectService) *SettingsHandler {
+	settingsHandler := &SettingsHandler{
+		Group:           group,
+		settingsService: settingsService,
+		projectService:  projectService,
+	}
+
+	group.GET("", settingsHandler.handleSettingsDialog).Name = "settings-dialog"
+	group.PUT("/calendars/:id", settingsHandler.handleCalendarSettings).Name = "calendar-settings"
+	group.PUT("/publishing", settingsHandler.handlePublishSettings).Name = "publish-settings"
+	group.PUT("/working-hours", settingsHandler.handleWorkingHours).Name = "working-hours"
+	group.PUT("/time-zone", settingsHandler.handleTimeZone).Name = "time-zone"
+	group.POST("/feeds", settingsHandler.handleAddFeed).Name = "add-feed"
+	group.DELETE("/feeds/:id", settingsHandler.handleRevokeFeed).Name = "revoke-feed"
+
+	return settingsHandler
+}
+
+func (h *SettingsHandler) handleSettingsDialog(c echo.Context) error {
+	settings, err := h.settingsService.GetSettings()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting settings", err)
+	}
+
+	projects, err := h.projectService.GetAllProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, pages.SettingsDialog(settings, projects, baseURL(c))); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handleCalendarSettings shows or hides a calendar or changes its color, then
+// has the timeline reload its events.
+func (h *SettingsHandler) handleCalendarSettings(c echo.Context) error {
+	// Calendar IDs can hold slashes, like the paths of CalDAV calendars
+	calendarID, err := url.PathUnescape(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "reading calendar ID", err)
+	}
+
+	changes := model.CalendarSettings{}
+	if err := c.Bind(&changes); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "reading calendar settings", err)
+	}
+
+	settings, err := h.settingsService.GetSettings()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting settings", err)
+	}
+
+	calendar := settings.Calendar(calendarID)
+	if calendar == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "updating calendar settings", utils.NewNotFoundError("calendar", calendarID))
+	}
+
+	calendar.Visible = changes.Visible
+	calendar.Color = changes.Color
+
+	err = h.settingsService.UpdateSettings(func(settings *model.Settings) error {
+		if known := settings.Calendar(calendarID); known != nil {
+			known.Visible = calendar.Visible
+			known.Color = calendar.Color
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "updating calendar settings", err)
+	}
+
+	if err := htmx.NewResponse().
+		AddTrigger(htmx.Trigger("calendar-changed")).
+		RenderTempl(c.Request().Context(), c.Response().Writer, pages.CalendarSettingsItem(*calendar)); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handlePublishSettings picks the calendar scheduled tasks are published to.
+func (h *SettingsHandler) handlePublishSettings(c echo.Context) error {
+	publishing := model.PublishSettings{}
+	if err := c.Bind(&publishing); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "reading publish settings", err)
+	}
+
+	var settings *model.Settings
+	err := h.settingsService.UpdateSettings(func(updated *model.Settings) error {
+		if publishing.IsEnabled() {
+			if calendar := updated.Calendar(publishing.CalendarID); calendar == nil || !calendar.Writable {
+				return fmt.Errorf("tasks can't be published to calendar %s", publishing.CalendarID)
+			}
+		}
+
+		updated.Publishing = publishing
+		settings = updated
+
+		return nil
+	})
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "updating publish settings", err)
+	}
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, pages.PublishSettingsForm(settings)); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handleWorkingHours sets when tasks scheduled without a time are fit in.
+// Hours that don't make sense are sent back to be fixed rather than saved.
+func (h *SettingsHandler) handleWorkingHours(c echo.Context) error {
+	hours := model.WorkingHours{}
+	if err := c.Bind(&hours); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "reading working hours", err)
+	}
+
+	failure := ""
+	if err := hours.Validate(); err != nil {
+		failure = err.Error()
+
+	} else {
+		err := h.settingsService.UpdateSettings(func(settings *model.Settings) error {
+			settings.WorkingHours = hours
+
+			return nil
+		})
+
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "updating working hours", err)
+		}
+	}
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, pages.WorkingHoursForm(hours, failure)); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handleTimeZone sets the time zone the user's days are in, going back to the
+// machine's when it's cleared. Zones that aren't known are sent back to be
+// fixed rather than saved. The timeline is redrawn for the days to line up.
+func (h *SettingsHandler) handleTimeZone(c echo.Context) error {
+	name := strings.TrimSpace(c.FormValue("timeZone"))
+
+	location := time.Local
+	if name != "" {
+		var err error
+		if location, err = time.LoadLocation(name); err != nil {
+			return h.renderTimeZone(c, htmx.NewResponse(), name, fmt.Sprintf("%s isn't a known time zone", name))
+		}
+	}
+
+	err := h.settingsService.UpdateSettings(func(settings *model.Settings) error {
+		settings.TimeZone = name
+
+		return nil
+	})
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "updating time zone", err)
+	}
+
+	utils.SetLocation(location)
+
+	return h.renderTimeZone(c, htmx.NewResponse().AddTrigger(htmx.Trigger("calendar-changed")), name, "")
+}
+
+func (h *SettingsHandler) renderTimeZone(c echo.Context, response htmx.Response, name string, failure string) error {
+	if err := response.RenderTempl(c.Request().Context(), c.Response().Writer, pages.TimeZoneForm(name, failure)); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handleAddFeed hands out a new feed of the scheduled tasks, of one project
+// when one is picked.
+func (h *SettingsHandler) handleAddFeed(c echo.Context) error {
+	if _, err := h.settingsService.AddFeed(c.FormValue("projectId")); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "adding feed", err)
+	}
+
+	return h.renderFeeds(c)
+}
+
+// handleRevokeFeed stops the feed's link from working, for when it was shared
+// with the wrong people.
+func (h *SettingsHandler) handleRevokeFeed(c echo.Context) error {
+	if err := h.settingsService.RevokeFeed(c.Param("id")); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "revoking feed", err)
+	}
+
+	return h.renderFeeds(c)
+}
+
+func (h *SettingsHandler) renderFeeds(c echo.Context) error {
+	settings, err := h.settingsService.GetSettings()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting settings", err)
+	}
+
+	projects, err := h.projectService.GetAllProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, pages.FeedSettings(settings.Feeds, projects, baseURL(c))); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// baseURL is where the app is reached at, for links opened outside of it.
+func baseURL(c echo.Context) string {
+	return c.Scheme() + "://" + c.Request().Host
+}
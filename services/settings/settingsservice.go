@@ -0,0 +1,142 @@
+package settings
+
+import (
+	"crypto/rand"
+	"encoding/gob"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/pleimann/camel-do/model"
+	bolt "go.etcd.io/bbolt"
+)
+
+// The settings are a single record in their own bucket
+var (
+	settingsBucket = []byte("settings")
+	settingsKey    = []byte("settings")
+)
+
+// SettingsService is a service for managing the app wide preferences.
+type SettingsService struct {
+	db *bolt.DB
+}
+
+func NewSettingsService(db *bolt.DB) (*SettingsService, error) {
+	settingsService := &SettingsService{
+		db: db,
+	}
+
+	gob.Register(model.Settings{})
+
+	return settingsService, nil
+}
+
+// GetSettings returns the saved settings, or the defaults before any are saved.
+func (s *SettingsService) GetSettings() (*model.Settings, error) {
+	slog.Debug("SettingsService.GetSettings")
+
+	settings := &model.Settings{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(settingsBucket)
+
+		if bucket == nil {
+			return nil
+		}
+
+		if settingsBytes := bucket.Get(settingsKey); settingsBytes != nil {
+			return settings.Unmarshal(settingsBytes)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("fetching settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+// UpdateSettings applies the change to the saved settings, reading and writing
+// them in one transaction so concurrent changes aren't lost.
+func (s *SettingsService) UpdateSettings(update func(settings *model.Settings) error) error {
+	slog.Debug("SettingsService.UpdateSettings")
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(settingsBucket)
+		if err != nil {
+			return err
+		}
+
+		settings := &model.Settings{}
+		if settingsBytes := bucket.Get(settingsKey); settingsBytes != nil {
+			if err := settings.Unmarshal(settingsBytes); err != nil {
+				return err
+			}
+		}
+
+		if err := update(settings); err != nil {
+			return err
+		}
+
+		settingsBytes, err := settings.Marshal()
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(settingsKey, settingsBytes)
+	})
+
+	if err != nil {
+		return fmt.Errorf("updating settings: %w", err)
+	}
+
+	return nil
+}
+
+// AddFeed hands out a new feed of the project's scheduled tasks, or of all of
+// them when projectID is empty.
+func (s *SettingsService) AddFeed(projectID string) (*model.Feed, error) {
+	slog.Debug("SettingsService.AddFeed", "projectId", projectID)
+
+	feed := model.Feed{
+		ID:        ulid.Make().String(),
+		Token:     rand.Text(),
+		ProjectID: projectID,
+		CreatedAt: time.Now(),
+	}
+
+	err := s.UpdateSettings(func(settings *model.Settings) error {
+		settings.Feeds = append(settings.Feeds, feed)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("adding feed: %w", err)
+	}
+
+	return &feed, nil
+}
+
+// RevokeFeed removes the feed, so its link stops working.
+func (s *SettingsService) RevokeFeed(id string) error {
+	slog.Debug("SettingsService.RevokeFeed", "id", id)
+
+	err := s.UpdateSettings(func(settings *model.Settings) error {
+		settings.Feeds = slices.DeleteFunc(settings.Feeds, func(feed model.Feed) bool {
+			return feed.ID == id
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("revoking feed %s: %w", id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package task
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/angelofallars/htmx-go"
+	"github.com/labstack/echo/v4"
+
+	"github.com/pleimann/camel-do/model"
+	"github.com/pleimann/camel-do/templates/blocks/activity"
+	"github.com/pleimann/camel-do/templates/components"
+	"github.com/pleimann/camel-do/templates/pages"
+	"github.com/pleimann/camel-do/utils"
+)
+
+func (h *TaskHandler) handleGetActivity(c echo.Context) error {
+	taskId := extractTaskId(c)
+
+	activities, err := h.activityService.GetTaskActivity(taskId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting activity", err)
+	}
+
+	projectsIndex, err := h.projectService.GetAllProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	threadTemplate := activity.ActivityThread(taskId, activities, projectsIndex)
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, threadTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+func (h *TaskHandler) handleAddComment(c echo.Context) error {
+	taskId := extractTaskId(c)
+
+	body := strings.TrimSpace(c.FormValue("body"))
+	if body == "" {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "comment is empty")
+	}
+
+	c.Logger().Debug("TaskHandler.handleAddComment", "taskId", taskId)
+
+	comment, err := h.activityService.AddComment(taskId, body)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "adding comment", err)
+
+		} else {
+			return fmt.Errorf("adding comment: %w", err)
+		}
+	}
+
+	return h.renderActivityItem(c, *comment)
+}
+
+func (h *TaskHandler) handleEditComment(c echo.Context) error {
+	taskId := extractTaskId(c)
+	commentId := c.Param("commentId")
+
+	activities, err := h.activityService.GetTaskActivity(taskId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting activity", err)
+	}
+
+	for comment := range activities.All() {
+		if comment.ID == commentId && comment.IsComment() {
+			editorTemplate := activity.CommentEditor(comment)
+
+			if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, editorTemplate); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+			}
+
+			return nil
+		}
+	}
+
+	return echo.NewHTTPError(http.StatusNotFound, "getting comment", utils.NewNotFoundError("comment", commentId))
+}
+
+func (h *TaskHandler) handleUpdateComment(c echo.Context) error {
+	taskId := extractTaskId(c)
+	commentId := c.Param("commentId")
+
+	body := strings.TrimSpace(c.FormValue("body"))
+	if body == "" {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "comment is empty")
+	}
+
+	c.Logger().Debug("TaskHandler.handleUpdateComment", "taskId", taskId, "commentId", commentId)
+
+	comment, err := h.activityService.UpdateComment(taskId, commentId, body)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "updating comment", err)
+
+		} else {
+			return fmt.Errorf("updating comment: %w", err)
+		}
+	}
+
+	return h.renderActivityItem(c, *comment)
+}
+
+func (h *TaskHandler) handleDeleteComment(c echo.Context) error {
+	taskId := extractTaskId(c)
+	commentId := c.Param("commentId")
+
+	c.Logger().Debug("TaskHandler.handleDeleteComment", "taskId", taskId, "commentId", commentId)
+
+	if err := h.activityService.DeleteComment(taskId, commentId); err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "deleting comment", err)
+
+		} else {
+			return fmt.Errorf("deleting comment: %w", err)
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *TaskHandler) renderActivityItem(c echo.Context, comment model.Activity) error {
+	projectsIndex, err := h.projectService.GetAllProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	itemTemplate := activity.ActivityItem(comment, projectsIndex)
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, itemTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+func (h *TaskHandler) handleSearchDialog(c echo.Context) error {
+	dialogTemplate := components.Dialog(pages.SearchDialog())
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, dialogTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+func (h *TaskHandler) handleSearch(c echo.Context) error {
+	tasks, err := h.taskService.SearchTasks(c.QueryParam("q"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "searching tasks", err)
+	}
+
+	projectsIndex, err := h.projectService.GetAllProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	resultsTemplate := pages.SearchResults(tasks, projectsIndex)
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, resultsTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handleExport downloads every task with its comments as JSON.
+func (h *TaskHandler) handleExport(c echo.Context) error {
+	tasks, err := h.taskService.GetAllTasks()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting tasks", err)
+	}
+
+	comments, err := h.activityService.GetAllComments()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting comments", err)
+	}
+
+	exported := make([]model.ExportedTask, 0, tasks.Len())
+	for task := range tasks.All() {
+		exported = append(exported, model.ExportedTask{
+			Task:     task,
+			Comments: comments[task.ID],
+		})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="camel-do-tasks.json"`)
+
+	return c.JSON(http.StatusOK, exported)
+}
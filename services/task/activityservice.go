@@ -0,0 +1,248 @@
+package task
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/pleimann/camel-do/model"
+	"github.com/pleimann/camel-do/utils"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Activity is kept in a bucket per task, nested in the activity bucket, keyed
+// by ULID so a cursor walks each thread in chronological order.
+var activityBucket = []byte("activity")
+
+// ActivityService is a service for managing the comment and event thread of
+// tasks.
+type ActivityService struct {
+	db *bolt.DB
+}
+
+func NewActivityService(db *bolt.DB) (*ActivityService, error) {
+	activityService := &ActivityService{
+		db: db,
+	}
+
+	return activityService, nil
+}
+
+func (s *ActivityService) GetTaskActivity(taskID string) (*model.ActivityList, error) {
+	slog.Debug("ActivityService.GetTaskActivity", "taskId", taskID)
+
+	activityList := model.NewActivityList()
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := taskActivityBucket(tx, taskID)
+
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(activityID, activityBytes []byte) error {
+			activity := model.Activity{}
+
+			if err := activity.Unmarshal(activityBytes); err != nil {
+				return err
+			}
+
+			activityList.Push(activity)
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("ActivityService.GetTaskActivity (%s): %w", taskID, err)
+	}
+
+	return activityList, nil
+}
+
+// GetAllComments returns the comments of every task keyed by task ID.
+func (s *ActivityService) GetAllComments() (map[string][]model.Activity, error) {
+	slog.Debug("ActivityService.GetAllComments")
+
+	comments := map[string][]model.Activity{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(activityBucket)
+
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEachBucket(func(taskID []byte) error {
+			return bucket.Bucket(taskID).ForEach(func(activityID, activityBytes []byte) error {
+				activity := model.Activity{}
+
+				if err := activity.Unmarshal(activityBytes); err != nil {
+					return err
+				}
+
+				if activity.IsComment() {
+					comments[activity.TaskID] = append(comments[activity.TaskID], activity)
+				}
+
+				return nil
+			})
+		})
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("ActivityService.GetAllComments: %w", err)
+	}
+
+	return comments, nil
+}
+
+func (s *ActivityService) AddComment(taskID string, body string) (*model.Activity, error) {
+	slog.Debug("ActivityService.AddComment", "taskId", taskID)
+
+	var comment *model.Activity
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if tasks := tx.Bucket([]byte("tasks")); tasks == nil || tasks.Get([]byte(taskID)) == nil {
+			return utils.NewNotFoundError("task", taskID)
+		}
+
+		var err error
+		comment, err = recordActivity(tx, model.Activity{
+			TaskID: taskID,
+			Kind:   model.CommentActivity,
+			Body:   body,
+		})
+
+		return err
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("ActivityService.AddComment (%s): %w", taskID, err)
+	}
+
+	return comment, nil
+}
+
+func (s *ActivityService) UpdateComment(taskID string, id string, body string) (*model.Activity, error) {
+	slog.Debug("ActivityService.UpdateComment", "taskId", taskID, "id", id)
+
+	comment := model.Activity{}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := commentBucket(tx, taskID, id, &comment)
+		if err != nil {
+			return err
+		}
+
+		comment.Body = body
+		comment.UpdatedAt = time.Now()
+
+		commentBytes, err := comment.Marshal()
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(id), commentBytes)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("ActivityService.UpdateComment (%s): %w", id, err)
+	}
+
+	return &comment, nil
+}
+
+func (s *ActivityService) DeleteComment(taskID string, id string) error {
+	slog.Debug("ActivityService.DeleteComment", "taskId", taskID, "id", id)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := commentBucket(tx, taskID, id, &model.Activity{})
+		if err != nil {
+			return err
+		}
+
+		return bucket.Delete([]byte(id))
+	})
+
+	if err != nil {
+		return fmt.Errorf("ActivityService.DeleteComment (%s): %w", id, err)
+	}
+
+	return nil
+}
+
+// commentBucket looks up a comment, refusing system events which can't be
+// edited or deleted, and returns the bucket it is stored in.
+func commentBucket(tx *bolt.Tx, taskID string, id string, comment *model.Activity) (*bolt.Bucket, error) {
+	bucket := taskActivityBucket(tx, taskID)
+	if bucket == nil {
+		return nil, utils.NewNotFoundError("comment", id)
+	}
+
+	commentBytes := bucket.Get([]byte(id))
+	if commentBytes == nil {
+		return nil, utils.NewNotFoundError("comment", id)
+	}
+
+	if err := comment.Unmarshal(commentBytes); err != nil {
+		return nil, err
+	}
+
+	if !comment.IsComment() {
+		return nil, fmt.Errorf("%s entries can't be changed", comment.Kind)
+	}
+
+	return bucket, nil
+}
+
+func taskActivityBucket(tx *bolt.Tx, taskID string) *bolt.Bucket {
+	bucket := tx.Bucket(activityBucket)
+
+	if bucket == nil {
+		return nil
+	}
+
+	return bucket.Bucket([]byte(taskID))
+}
+
+// recordActivity appends an entry to a task's thread within an existing
+// transaction so it is stored atomically with the change it describes.
+func recordActivity(tx *bolt.Tx, activity model.Activity) (*model.Activity, error) {
+	bucket, err := tx.CreateBucketIfNotExists(activityBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	taskBucket, err := bucket.CreateBucketIfNotExists([]byte(activity.TaskID))
+	if err != nil {
+		return nil, err
+	}
+
+	activity.ID = ulid.Make().String()
+	activity.CreatedAt = time.Now()
+	activity.UpdatedAt = activity.CreatedAt
+
+	activityBytes, err := activity.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := taskBucket.Put([]byte(activity.ID), activityBytes); err != nil {
+		return nil, err
+	}
+
+	return &activity, nil
+}
+
+// deleteActivity removes a task's whole thread along with the task.
+func deleteActivity(tx *bolt.Tx, taskID string) error {
+	bucket := tx.Bucket(activityBucket)
+
+	if bucket == nil || bucket.Bucket([]byte(taskID)) == nil {
+		return nil
+	}
+
+	return bucket.DeleteBucket([]byte(taskID))
+}
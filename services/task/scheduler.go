@@ -0,0 +1,123 @@
+package task
+
+import (
+	"slices"
+	"time"
+
+	"github.com/pleimann/camel-do/model"
+)
+
+// slotStep is the grid tasks are fit in on, the timeline's quarter hours.
+const slotStep = 15 * time.Minute
+
+// schedulingHorizon is how many days ahead free time is looked for.
+const schedulingHorizon = 14
+
+// scheduleAlternativeCount is how many other free times are offered besides
+// the one a task is fit into.
+const scheduleAlternativeCount = 3
+
+// busyPeriod is time taken up by a meeting or another task.
+type busyPeriod struct {
+	Start time.Time
+	End   time.Time
+}
+
+// busyPeriods are the times the scheduled tasks and the events take up. The
+// task being scheduled doesn't get in its own way.
+func busyPeriods(tasks *model.TaskList, events *model.EventList, taskID string) []busyPeriod {
+	var busy []busyPeriod
+
+	for task := range tasks.All() {
+		if task.ID == taskID || !task.StartTime.Valid || task.AllDay.Bool || task.Status == model.Cancelled {
+			continue
+		}
+
+		busy = append(busy, busyPeriod{Start: task.StartTime.Time, End: task.End()})
+	}
+
+	for event := range events.All() {
+		if event.Busy() {
+			busy = append(busy, busyPeriod{Start: event.StartTime.Time, End: event.End()})
+		}
+	}
+
+	return busy
+}
+
+// freeSlots finds up to count times, from the earliest on, at which the length
+// of time fits within working hours without running into anything busy. Each
+// is in a different gap between busy times, so those after the first are
+// real alternatives to it rather than the same gap a little later.
+func freeSlots(busy []busyPeriod, from time.Time, length time.Duration, hours model.WorkingHours, count int) []time.Time {
+	// Tasks without a duration still take up their slot on the timeline
+	length = max(length, slotStep)
+
+	busy = slices.Clone(busy)
+	slices.SortFunc(busy, func(a, b busyPeriod) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	clash := func(start time.Time, end time.Time) (busyPeriod, bool) {
+		for _, period := range busy {
+			if period.Start.Before(end) && period.End.After(start) {
+				return period, true
+			}
+		}
+
+		return busyPeriod{}, false
+	}
+
+	var slots []time.Time
+
+	// Days are picked at noon, some zones skip midnight when the clocks change
+	year, month, day := from.Date()
+	for i := 0; i < schedulingHorizon && len(slots) < count; i++ {
+		workStart, workEnd, ok := hours.On(time.Date(year, month, day+i, 12, 0, 0, 0, from.Location()))
+		if !ok {
+			continue
+		}
+
+		start := workStart
+		if from.After(start) {
+			start = roundUpToSlot(from)
+		}
+
+		for !start.Add(length).After(workEnd) && len(slots) < count {
+			if period, ok := clash(start, start.Add(length)); ok {
+				start = roundUpToSlot(period.End)
+				continue
+			}
+
+			slots = append(slots, start)
+
+			// The gap lasts until whatever's next, the alternative is after it
+			next := slices.IndexFunc(busy, func(period busyPeriod) bool {
+				return !period.Start.Before(start)
+			})
+
+			if next < 0 {
+				break
+			}
+
+			start = roundUpToSlot(busy[next].End)
+		}
+	}
+
+	return slots
+}
+
+// roundUpToSlot is the first start of a slot at or after t. Slots are counted
+// from midnight so they stay on the quarter hours in zones that are offset from
+// UTC by less than an hour.
+func roundUpToSlot(t time.Time) time.Time {
+	year, month, day := t.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+
+	sinceMidnight := t.Sub(midnight)
+	if rest := sinceMidnight % slotStep; rest != 0 {
+		sinceMidnight += slotStep - rest
+	}
+
+	return midnight.Add(sinceMidnight)
+}
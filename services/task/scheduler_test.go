@@ -0,0 +1,206 @@
+package task
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/guregu/null/v6/zero"
+	"github.com/pleimann/camel-do/model"
+)
+
+func TestFreeSlots(t *testing.T) {
+	// Kathmandu is 5:45 ahead of UTC, so quarter hours there aren't UTC's
+	kathmandu := time.FixedZone("Asia/Kathmandu", 5*60*60+45*60)
+
+	// Monday June 2nd 2025
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2025, 6, day, hour, minute, 0, 0, kathmandu)
+	}
+
+	busy := func(day int, hour int, minute int, minutes int) busyPeriod {
+		start := at(day, hour, minute)
+		return busyPeriod{Start: start, End: start.Add(time.Duration(minutes) * time.Minute)}
+	}
+
+	tests := []struct {
+		name   string
+		busy   []busyPeriod
+		from   time.Time
+		length time.Duration
+		count  int
+		want   []time.Time
+	}{
+		{
+			name:   "starts at the next quarter hour when free",
+			from:   at(2, 10, 7),
+			length: 30 * time.Minute,
+			count:  1,
+			want:   []time.Time{at(2, 10, 15)},
+		},
+		{
+			name:   "waits out a meeting going on",
+			busy:   []busyPeriod{busy(2, 10, 0, 50)},
+			from:   at(2, 10, 7),
+			length: 30 * time.Minute,
+			count:  1,
+			want:   []time.Time{at(2, 11, 0)},
+		},
+		{
+			name:   "skips gaps too short for the task",
+			busy:   []busyPeriod{busy(2, 9, 0, 60), busy(2, 10, 30, 90)},
+			from:   at(2, 8, 0),
+			length: time.Hour,
+			count:  1,
+			want:   []time.Time{at(2, 12, 0)},
+		},
+		{
+			name:   "offers alternatives in the later gaps",
+			busy:   []busyPeriod{busy(2, 10, 0, 60), busy(2, 13, 0, 120), busy(2, 16, 0, 60)},
+			from:   at(2, 9, 0),
+			length: 30 * time.Minute,
+			count:  4,
+			want:   []time.Time{at(2, 9, 0), at(2, 11, 0), at(2, 15, 0), at(3, 9, 0)},
+		},
+		{
+			name:   "overlapping meetings are waited out together",
+			busy:   []busyPeriod{busy(2, 9, 0, 60), busy(2, 9, 30, 90)},
+			from:   at(2, 9, 0),
+			length: 15 * time.Minute,
+			count:  1,
+			want:   []time.Time{at(2, 11, 0)},
+		},
+		{
+			name:   "moves past the weekend after Friday's work",
+			from:   at(6, 16, 50),
+			length: 30 * time.Minute,
+			count:  1,
+			want:   []time.Time{at(9, 9, 0)},
+		},
+		{
+			name:  "tasks without a duration take a slot",
+			busy:  []busyPeriod{busy(2, 9, 15, 450)},
+			from:  at(2, 9, 0),
+			count: 2,
+			want:  []time.Time{at(2, 9, 0), at(2, 16, 45)},
+		},
+		{
+			name:   "a task longer than the working day never fits",
+			from:   at(2, 9, 0),
+			length: 9 * time.Hour,
+			count:  1,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := freeSlots(tt.busy, tt.from, tt.length, model.DefaultWorkingHours, tt.count)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("freeSlots() = %v, want %v", got, tt.want)
+			}
+
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("freeSlots()[%d] = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFreeSlotsAcrossDaylightSaving(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The small hours of Sundays, when the clocks change
+	hours := model.WorkingHours{Start: "01:00", End: "04:00", Days: []time.Weekday{time.Sunday}}
+
+	// Instants are given in UTC, in New York the hour after the clocks go
+	// back happens twice
+	utc := func(month time.Month, day int, hour int, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		busy   []busyPeriod
+		from   time.Time
+		length time.Duration
+		want   []time.Time
+	}{
+		{
+			name:   "the night clocks go forward is an hour shorter",
+			from:   time.Date(2025, 3, 9, 0, 0, 0, 0, newYork),
+			length: 150 * time.Minute,
+			want:   []time.Time{time.Date(2025, 3, 16, 1, 0, 0, 0, newYork)},
+		},
+		{
+			name:   "the night clocks go back is an hour longer",
+			from:   time.Date(2025, 11, 2, 0, 0, 0, 0, newYork),
+			length: 210 * time.Minute,
+			want:   []time.Time{utc(11, 2, 5, 0)},
+		},
+		{
+			name:   "slots stay on the quarter hours after the clocks go back",
+			busy:   []busyPeriod{{Start: utc(11, 2, 5, 0), End: utc(11, 2, 6, 50)}},
+			from:   time.Date(2025, 11, 2, 0, 0, 0, 0, newYork),
+			length: 30 * time.Minute,
+			want:   []time.Time{utc(11, 2, 7, 0)},
+		},
+		{
+			name:   "meetings set in other zones are waited out where the user is",
+			busy:   []busyPeriod{{Start: utc(11, 2, 5, 0), End: time.Date(2025, 11, 2, 12, 35, 0, 0, time.FixedZone("Asia/Kathmandu", 5*60*60+45*60))}},
+			from:   time.Date(2025, 11, 2, 0, 0, 0, 0, newYork),
+			length: 30 * time.Minute,
+			want:   []time.Time{utc(11, 2, 7, 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := freeSlots(tt.busy, tt.from, tt.length, hours, 1)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("freeSlots() = %v, want %v", got, tt.want)
+			}
+
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("freeSlots()[%d] = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBusyPeriods(t *testing.T) {
+	start := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+
+	tasks := model.NewTaskList()
+	tasks.Push(model.Task{ID: "scheduling", StartTime: zero.TimeFrom(start), Duration: zero.Int32From(30)})
+	tasks.Push(model.Task{ID: "review", StartTime: zero.TimeFrom(start), Duration: zero.Int32From(45)})
+	tasks.Push(model.Task{ID: "dropped", StartTime: zero.TimeFrom(start), Duration: zero.Int32From(45), Status: model.Cancelled})
+	tasks.Push(model.Task{ID: "trip", StartTime: zero.TimeFrom(start), AllDay: zero.BoolFrom(true)})
+
+	events := model.NewEventList()
+	events.Push(model.Event{Task: model.Task{ID: "standup", StartTime: zero.TimeFrom(start), Duration: zero.Int32From(15)}})
+	events.Push(model.Event{Task: model.Task{ID: "skipped", StartTime: zero.TimeFrom(start), Duration: zero.Int32From(60)}, Declined: true})
+	events.Push(model.Event{Task: model.Task{ID: "reminder", StartTime: zero.TimeFrom(start), Duration: zero.Int32From(60)}, Free: true})
+
+	busy := busyPeriods(tasks, events, "scheduling")
+
+	want := []time.Duration{45 * time.Minute, 15 * time.Minute}
+	if len(busy) != len(want) {
+		t.Fatalf("busyPeriods() = %v, want the review and the standup", busy)
+	}
+
+	for i, period := range busy {
+		if period.End.Sub(period.Start) != want[i] {
+			t.Errorf("busyPeriods()[%d] lasts %s, want %s", i, period.End.Sub(period.Start), want[i])
+		}
+	}
+}
@@ -60,8 +60,8 @@ func GenerateRandomTask() model.Task {
 	// Generate random duration between 15 minutes and 4 hours.
 	duration := int32(math.Round(rand.Float64()*4.0) * 15)
 
-	// Generate random completed status.
-	completed := rand.IntN(3) == 1
+	// Generate random status.
+	status := model.StatusValues()[rand.IntN(len(model.StatusValues()))]
 
 	createdAt := time.Now().Add(time.Duration(-rand.IntN(7*24)) * time.Hour)
 	updatedAt := createdAt.Add(time.Duration(rand.IntN(72)) * time.Hour)
@@ -75,8 +75,8 @@ func GenerateRandomTask() model.Task {
 		ProjectID:   zero.StringFromPtr(nil),
 		StartTime:   startTime,
 		Duration:    zero.Int32From(duration),
-		Completed:   zero.BoolFrom(completed), // default to not completed.
-		CreatedAt:   createdAt,                // Set the creation timestamp
-		UpdatedAt:   updatedAt,                // Set the update timestamp
+		Status:      status,
+		CreatedAt:   createdAt, // Set the creation timestamp
+		UpdatedAt:   updatedAt, // Set the update timestamp
 	}
 }
@@ -1,8 +1,10 @@
 package task
 
 import (
+	"context"
 	"fmt"
 	"html/template"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -25,38 +27,71 @@ import (
 type TaskHandler struct {
 	*echo.Group
 	taskService     *TaskService
+	activityService *ActivityService
 	projectService  *project.ProjectService
 	calendarService CalendarService
+	settingsService SettingsService
+}
+
+// SettingsService interface to avoid circular dependencies
+type SettingsService interface {
+	GetSettings() (*model.Settings, error)
 }
 
 // CalendarService interface to avoid circular dependencies
 type CalendarService interface {
-	GetTodaysEvents() (*model.EventList, error)
+	GetEvent(id string) (*model.Event, error)
+	GetEventsOnDate(date time.Time) (*model.EventList, error)
+	GetEventsBetween(start time.Time, end time.Time) (*model.EventList, error)
+	PublishTask(ctx context.Context, task *model.Task) (string, string, error)
+	UnpublishTask(ctx context.Context, task *model.Task) error
 }
 
 func NewTaskHandler(
-	group *echo.Group, taskService *TaskService, projectsService *project.ProjectService, calendarService CalendarService,
+	group *echo.Group,
+	taskService *TaskService,
+	activityService *ActivityService,
+	projectsService *project.ProjectService,
+	calendarService CalendarService,
+	settingsService SettingsService,
 ) *TaskHandler {
 	taskHandler := &TaskHandler{
 		Group:           group,
 		taskService:     taskService,
+		activityService: activityService,
 		projectService:  projectsService,
 		calendarService: calendarService,
+		settingsService: settingsService,
 	}
 
 	group.POST("", taskHandler.handleCreateTask).Name = "create-task"
 
 	group.GET("/new", taskHandler.handleNewTask).Name = "new-task"
 	group.GET("/edit/:id", taskHandler.handleEditTask).Name = "edit-task"
+	group.GET("/fields", taskHandler.handleGetCustomFields).Name = "task-custom-fields"
+	group.GET("/backlog", taskHandler.handleGetBacklog).Name = "backlog"
+	group.GET("/list", taskHandler.handleGetTasklist).Name = "tasklist"
+	group.GET("/search", taskHandler.handleSearchDialog).Name = "search-dialog"
+	group.GET("/search/results", taskHandler.handleSearch).Name = "search-tasks"
+	group.GET("/export", taskHandler.handleExport).Name = "export-tasks"
+	group.GET("/from-event", taskHandler.handleEventTasks).Name = "event-tasks"
 
 	group.PUT("/:id", taskHandler.handleTaskUpdate).Name = "update-task"
 	group.DELETE("/:id", taskHandler.handleTaskDelete).Name = "delete-task"
 	group.PUT("/:id/complete", taskHandler.handleTaskComplete).Name = "complete-task"
-	group.PUT("/:id/hide", taskHandler.handleTaskHide).Name = "hide-task"
+	group.GET("/:id/status", taskHandler.handleStatusMenu).Name = "task-status-menu"
+	group.PUT("/:id/status", taskHandler.handleTaskStatus).Name = "task-status"
 	group.GET("/:id/schedule", taskHandler.handleScheduleDialog).Name = "schedule-dialog"
 	group.PUT("/:id/schedule", taskHandler.handleScheduleTask).Name = "schedule-task"
 	group.DELETE("/:id/schedule", taskHandler.handleUnScheduleTask).Name = "unschedule-task"
 
+	group.GET("/:id/subtasks", taskHandler.handleGetSubtasks).Name = "task-subtasks"
+	group.GET("/:id/activity", taskHandler.handleGetActivity).Name = "task-activity"
+	group.POST("/:id/comments", taskHandler.handleAddComment).Name = "add-comment"
+	group.GET("/:id/comments/:commentId/edit", taskHandler.handleEditComment).Name = "edit-comment"
+	group.PUT("/:id/comments/:commentId", taskHandler.handleUpdateComment).Name = "update-comment"
+	group.DELETE("/:id/comments/:commentId", taskHandler.handleDeleteComment).Name = "delete-comment"
+
 	return taskHandler
 }
 
@@ -78,7 +113,28 @@ func (h *TaskHandler) handleNewTask(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
 	}
 
-	newTaskDialogTemplate := pages.TaskDialog(projectsIndex, nil)
+	// Follow-ups of an event start out from it, in the project it's about
+	var followUp *model.Task
+	if eventID := c.QueryParam("eventId"); eventID != "" {
+		event, err := h.calendarService.GetEvent(eventID)
+		if err != nil {
+			if utils.IsNotFoundError(err) {
+				return echo.NewHTTPError(http.StatusNotFound, "getting event", err)
+
+			} else {
+				return echo.NewHTTPError(http.StatusInternalServerError, "getting event", err)
+			}
+		}
+
+		task := event.FollowUpTask()
+		if projectID := projectsIndex.MatchEvent(*event); projectID != "" {
+			task.ProjectID = zero.StringFrom(projectID)
+		}
+
+		followUp = &task
+	}
+
+	newTaskDialogTemplate := pages.TaskDialog(projectsIndex, followUp)
 
 	dialogTemplate := components.Dialog(newTaskDialogTemplate)
 
@@ -103,7 +159,7 @@ func (h *TaskHandler) handleEditTask(c echo.Context) error {
 
 	}
 
-	projectsIndex, err := h.projectService.GetProjects()
+	projectsIndex, err := h.projectService.GetAllProjects()
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
@@ -120,6 +176,152 @@ func (h *TaskHandler) handleEditTask(c echo.Context) error {
 	return nil
 }
 
+// handleEventTasks lists the tasks created to follow up on an event, whether
+// or not the event is still on the calendar.
+func (h *TaskHandler) handleEventTasks(c echo.Context) error {
+	eventID := c.QueryParam("eventId")
+	if eventID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "no event to list the tasks of")
+	}
+
+	event, err := h.calendarService.GetEvent(eventID)
+	if err != nil {
+		if !utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting event", err)
+		}
+
+		event = nil
+	}
+
+	tasks, err := h.taskService.GetEventTasks(eventID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting event tasks", err)
+	}
+
+	projectsIndex, err := h.projectService.GetAllProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	dialogTemplate := components.Dialog(pages.EventTasksDialog(eventID, event, tasks, projectsIndex))
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, dialogTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+func (h *TaskHandler) handleGetCustomFields(c echo.Context) error {
+	projectId := c.QueryParam("projectId")
+
+	if projectId == "" {
+		return c.NoContent(http.StatusOK)
+	}
+
+	project, err := h.projectService.GetProject(projectId)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "getting project", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting project", err)
+		}
+	}
+
+	var task *model.Task
+	if taskId := extractTaskId(c); taskId != "" {
+		if task, err = h.taskService.GetTask(taskId); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting task", err)
+		}
+	}
+
+	customFieldsTemplate := pages.TaskCustomFields(project, task)
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, customFieldsTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+func (h *TaskHandler) handleGetSubtasks(c echo.Context) error {
+	taskId := extractTaskId(c)
+
+	subtasks, err := h.taskService.GetSubtasks(taskId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting subtasks", err)
+	}
+
+	subtasksTemplate := pages.TaskSubtasks(subtasks)
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, subtasksTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+func (h *TaskHandler) handleGetBacklog(c echo.Context) error {
+	var filter model.TaskFilter
+	if err := c.Bind(&filter); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "decoding filter", err)
+	}
+
+	backlogTasks, err := h.taskService.GetBacklogTasks()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting backlog tasks", err)
+	}
+
+	projectsIndex, err := h.projectService.GetAllProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	backlogTemplate := backlog.Backlog(filter.Apply(backlogTasks, projectsIndex), projectsIndex)
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, backlogTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handleGetTasklist lists the tasks scheduled on a day, today unless one is
+// given, narrowed down by status like the backlog.
+func (h *TaskHandler) handleGetTasklist(c echo.Context) error {
+	var filter model.TaskFilter
+	if err := c.Bind(&filter); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "decoding filter", err)
+	}
+
+	date := utils.Now()
+	if dateStr := c.QueryParam("date"); dateStr != "" {
+		var err error
+		if date, err = utils.ParseDate("20060102", dateStr); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid date format", err)
+		}
+	}
+
+	tasks, err := h.taskService.GetTasksScheduledOnDate(date)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting tasks", err)
+	}
+
+	projectsIndex, err := h.projectService.GetAllProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	tasklistTemplate := tasklist.TasklistView(date, filter.Apply(tasks, projectsIndex), projectsIndex, filter.Status)
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, tasklistTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
 func (h *TaskHandler) handleScheduleDialog(c echo.Context) error {
 	taskId := extractTaskId(c)
 
@@ -155,18 +357,21 @@ func (h *TaskHandler) handleScheduleTask(c echo.Context) error {
 
 	// Parse date and time
 	var scheduledTime time.Time
+	var alternatives []time.Time
 	var err error
 
 	if dateStr == "" && timeStr == "" {
-		// Fall back to default scheduling (next 15-minute slot)
-		scheduledTime = time.Now().Truncate(15 * time.Minute).Add(15 * time.Minute)
+		// Fit the task into the first free time from now on
+		scheduledTime, alternatives = h.firstFreeTime(taskId, utils.Now())
 
 	} else {
-		var parsedDate time.Time
+		// Without a date the time is today's
+		parsedDate := utils.Now()
 
 		if dateStr != "" {
-			// Parse the date (YYYYMMDD format)
-			parsedDate, err = time.Parse("20060102", dateStr)
+			// Parse the date (YYYYMMDD format) as the user's day, in UTC it
+			// would be the day before west of it
+			parsedDate, err = utils.ParseDate("20060102", dateStr)
 			if err != nil {
 				return echo.NewHTTPError(http.StatusBadRequest, "invalid date format", err)
 			}
@@ -179,20 +384,47 @@ func (h *TaskHandler) handleScheduleTask(c echo.Context) error {
 			if err != nil {
 				return echo.NewHTTPError(http.StatusBadRequest, "invalid time format", err)
 			}
+		} else if preferred, ok := h.preferredStartTime(taskId, parsedDate); ok {
+			parsedTime = preferred
+
 		} else {
-			// Set to nearest 15-minute increment in the future
-			parsedTime = time.Now().Truncate(15 * time.Minute).Add(15 * time.Minute)
+			// Fit the task into the first free time of the day, from now on
+			// when it's today
+			from := utils.StartOfDay(parsedDate)
+			if now := utils.Now(); now.After(from) && now.Before(from.AddDate(0, 0, 1)) {
+				from = now
+			}
+
+			// The day can be full, the free time is then on a later one
+			parsedTime, alternatives = h.firstFreeTime(taskId, from)
+			parsedDate = parsedTime
 		}
 
 		// Combine date and time
 		scheduledTime = time.Date(
 			parsedDate.Year(), parsedDate.Month(), parsedDate.Day(),
 			parsedTime.Hour(), parsedTime.Minute(), 0, 0,
-			time.Local,
+			utils.Location(),
 		)
 	}
 
-	if err := h.taskService.ScheduleTask(taskId, zero.TimeFrom(scheduledTime)); err != nil {
+	if c.FormValue("allDay") == "true" {
+		// The until date is inclusive while the task's end is exclusive
+		endDate := scheduledTime
+		if endDateStr := c.FormValue("endDate"); endDateStr != "" {
+			endDate, err = utils.ParseDate("2006-01-02", endDateStr)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid end date format", err)
+			}
+		}
+
+		err = h.taskService.ScheduleTaskAllDay(taskId, scheduledTime, endDate.AddDate(0, 0, 1))
+
+	} else {
+		err = h.taskService.ScheduleTask(taskId, zero.TimeFrom(scheduledTime))
+	}
+
+	if err != nil {
 		if utils.IsNotFoundError(err) {
 			return echo.NewHTTPError(http.StatusNotFound, "scheduling task", err)
 		} else {
@@ -200,12 +432,14 @@ func (h *TaskHandler) handleScheduleTask(c echo.Context) error {
 		}
 	}
 
+	h.publishTask(c, taskId)
+
 	task, err := h.taskService.GetTask(taskId)
 	if err != nil {
 		return fmt.Errorf("getting task: %w", err)
 	}
 
-	projectsIndex, err := h.projectService.GetProjects()
+	projectsIndex, err := h.projectService.GetAllProjects()
 	if err != nil {
 		return fmt.Errorf("getting projects: %w", err)
 	}
@@ -213,13 +447,13 @@ func (h *TaskHandler) handleScheduleTask(c echo.Context) error {
 	project := projectsIndex.Get(task.ProjectID.String)
 
 	// Get timeline data for out-of-band update
-	timelineDate := scheduledTime.Truncate(24 * time.Hour)
+	timelineDate := utils.StartOfDay(scheduledTime)
 	timelineTasks, err := h.taskService.GetTasksScheduledOnDate(timelineDate)
 	if err != nil {
 		return fmt.Errorf("getting timeline tasks: %w", err)
 	}
 
-	timelineEvents, err := h.calendarService.GetTodaysEvents()
+	timelineEvents, err := h.calendarService.GetEventsOnDate(timelineDate)
 	if err != nil {
 		return fmt.Errorf("getting timeline events: %w", err)
 	}
@@ -232,12 +466,20 @@ func (h *TaskHandler) handleScheduleTask(c echo.Context) error {
 	timelineOOBTemplate := templ.Raw(`<div hx-swap-oob="innerHTML:#timeline-grid">`)
 	timelineOOBTemplateEnd := templ.Raw(`</div>`)
 
+	allDayLaneTemplate := timeline.AllDayLaneContent(timelineDate, timelineTasks, timelineEvents, projectsIndex)
+	allDayOOBTemplate := templ.Raw(fmt.Sprintf(`<div hx-swap-oob="innerHTML:#%s">`, timeline.AllDayLaneSelector))
+
 	// Create multi-response
 	multiResponse := components.MultiResponse(
 		taskViewTemplate,
 		timelineOOBTemplate,
 		timelineGridTemplate,
 		timelineOOBTemplateEnd,
+		allDayOOBTemplate,
+		allDayLaneTemplate,
+		timelineOOBTemplateEnd,
+		h.budgetWarning(task, projectsIndex),
+		scheduleAlternatives(task, scheduledTime, alternatives),
 	)
 
 	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, multiResponse); err != nil {
@@ -256,7 +498,7 @@ func (h *TaskHandler) handleUnScheduleTask(c echo.Context) error {
 		return fmt.Errorf("getting task before unschedule: %w", err)
 	}
 
-	timelineDate := taskBeforeUnschedule.StartTime.Time.Truncate(24 * time.Hour)
+	timelineDate := utils.StartOfDay(taskBeforeUnschedule.StartTime.Time)
 
 	if err := h.taskService.ScheduleTask(taskId, zero.TimeFromPtr(nil)); err != nil {
 		if utils.IsNotFoundError(err) {
@@ -267,12 +509,14 @@ func (h *TaskHandler) handleUnScheduleTask(c echo.Context) error {
 		}
 	}
 
+	h.publishTask(c, taskId)
+
 	task, err := h.taskService.GetTask(taskId)
 	if err != nil {
 		return fmt.Errorf("getting task: %w", err)
 	}
 
-	projectsIndex, err := h.projectService.GetProjects()
+	projectsIndex, err := h.projectService.GetAllProjects()
 	if err != nil {
 		return fmt.Errorf("getting projects: %w", err)
 	}
@@ -285,7 +529,7 @@ func (h *TaskHandler) handleUnScheduleTask(c echo.Context) error {
 		return fmt.Errorf("getting timeline tasks: %w", err)
 	}
 
-	timelineEvents, err := h.calendarService.GetTodaysEvents()
+	timelineEvents, err := h.calendarService.GetEventsOnDate(timelineDate)
 	if err != nil {
 		return fmt.Errorf("getting timeline events: %w", err)
 	}
@@ -298,12 +542,18 @@ func (h *TaskHandler) handleUnScheduleTask(c echo.Context) error {
 	timelineOOBTemplate := templ.Raw(`<div hx-swap-oob="innerHTML:#timeline-grid">`)
 	timelineOOBTemplateEnd := templ.Raw(`</div>`)
 
+	allDayLaneTemplate := timeline.AllDayLaneContent(timelineDate, timelineTasks, timelineEvents, projectsIndex)
+	allDayOOBTemplate := templ.Raw(fmt.Sprintf(`<div hx-swap-oob="innerHTML:#%s">`, timeline.AllDayLaneSelector))
+
 	// Create multi-response
 	multiResponse := components.MultiResponse(
 		taskCardTemplate,
 		timelineOOBTemplate,
 		timelineGridTemplate,
 		timelineOOBTemplateEnd,
+		allDayOOBTemplate,
+		allDayLaneTemplate,
+		timelineOOBTemplateEnd,
 	)
 
 	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, multiResponse); err != nil {
@@ -319,12 +569,6 @@ func (h *TaskHandler) handleCreateTask(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusUnprocessableEntity, "decoding form data", err)
 	}
 
-	c.Logger().Debug("TaskHandler.handleTaskCreate", "task", task)
-
-	if err := h.taskService.AddTask(task); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "adding task", err)
-	}
-
 	c.Logger().Debug("TaskHandler.handleTaskCreate: get project", "projectId", task.ProjectID)
 
 	var err error
@@ -342,6 +586,22 @@ func (h *TaskHandler) handleCreateTask(c echo.Context) error {
 		}
 	}
 
+	if err := bindCustomValues(c, task, project); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	bindMilestone(task, project)
+
+	if project != nil {
+		project.Defaults.Apply(task, utils.Now())
+	}
+
+	c.Logger().Debug("TaskHandler.handleTaskCreate", "task", task)
+
+	if err := h.taskService.AddTask(task); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "adding task", err)
+	}
+
 	if task.StartTime.Valid {
 		// TODO Else it might belong on today's timeline but just close the dialog for now
 		if _, err := htmx.NewResponse().
@@ -379,23 +639,23 @@ func (h *TaskHandler) handleTaskUpdate(c echo.Context) error {
 
 	taskId := extractTaskId(c)
 
-	task := &model.Task{
-		ID: taskId,
+	// Bind onto the stored task so fields the dialog doesn't edit are kept
+	task, err := h.taskService.GetTask(taskId)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "getting task", err)
+
+		} else {
+			return fmt.Errorf("getting task: %w", err)
+		}
 	}
 
 	if err := c.Bind(task); err != nil {
 		return echo.NewHTTPError(http.StatusUnprocessableEntity, "decoding form data", err)
 	}
 
-	c.Logger().Debug("TaskHandler.handleTaskUpdate", "task", task)
-
-	if err := h.taskService.UpdateTask(task); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "adding task", err)
-	}
-
 	c.Logger().Debug("TaskHandler.handleTaskUpdate: get project", "projectId", task.ProjectID)
 
-	var err error
 	var project *model.Project
 	if task.ProjectID.Valid {
 		project, err = h.projectService.GetProject(task.ProjectID.ValueOrZero())
@@ -410,6 +670,20 @@ func (h *TaskHandler) handleTaskUpdate(c echo.Context) error {
 		}
 	}
 
+	if err := bindCustomValues(c, task, project); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	bindMilestone(task, project)
+
+	c.Logger().Debug("TaskHandler.handleTaskUpdate", "task", task)
+
+	if err := h.taskService.UpdateTask(task); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "adding task", err)
+	}
+
+	h.publishTask(c, taskId)
+
 	if task.StartTime.Valid {
 		// TODO Else it might belong on today's timeline but just close the dialog for now
 		c.Logger().Debug("TaskHandler.handleTaskUpdate: closing task dialog", "task", task)
@@ -445,6 +719,9 @@ func (h *TaskHandler) handleTaskDelete(c echo.Context) error {
 
 	c.Logger().Debug("TaskHandler.handleTaskDelete", "taskId", taskId)
 
+	// Subtasks are deleted along with the task so their events go too
+	published := h.publishedTasks(taskId)
+
 	if err := h.taskService.DeleteTask(taskId); err != nil {
 		if utils.IsNotFoundError(err) {
 			return echo.NewHTTPError(http.StatusNotFound, "deleting task", err)
@@ -454,6 +731,12 @@ func (h *TaskHandler) handleTaskDelete(c echo.Context) error {
 		}
 	}
 
+	for _, task := range published {
+		if err := h.calendarService.UnpublishTask(c.Request().Context(), &task); err != nil {
+			slog.Warn("unpublishing deleted task", "taskId", task.ID, "error", err)
+		}
+	}
+
 	if err := c.NoContent(http.StatusNoContent); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "rendering template", err)
 	}
@@ -472,67 +755,303 @@ func (h *TaskHandler) handleTaskComplete(c echo.Context) error {
 		if utils.IsNotFoundError(err) {
 			return echo.NewHTTPError(http.StatusNotFound, "updating task", err)
 
+		} else if model.IsTransitionError(err) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+
 		} else {
 			return fmt.Errorf("updating task: %w", err)
 		}
 	}
 
-	if task, err := h.taskService.GetTask(taskId); err != nil {
+	h.publishTask(c, taskId)
+
+	return h.renderChangedTask(c, taskId)
+}
+
+func (h *TaskHandler) handleStatusMenu(c echo.Context) error {
+	taskId := extractTaskId(c)
+
+	task, err := h.taskService.GetTask(taskId)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "getting task", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting task", err)
+		}
+	}
+
+	menuTemplate := components.StatusMenu(*task, h.taskService.Workflow().Next(task.Status))
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, menuTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+func (h *TaskHandler) handleTaskStatus(c echo.Context) error {
+	defer c.Request().Body.Close()
+
+	taskId := extractTaskId(c)
+
+	status, err := model.ParseStatusString(c.FormValue("status"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "invalid status", err)
+	}
+
+	c.Logger().Debug("TaskHandler.handleTaskStatus", "taskId", taskId, "status", status)
+
+	if err := h.taskService.SetTaskStatus(taskId, status); err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "updating task status", err)
+
+		} else if model.IsTransitionError(err) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+
+		} else {
+			return fmt.Errorf("updating task status: %w", err)
+		}
+	}
+
+	h.publishTask(c, taskId)
+
+	return h.renderChangedTask(c, taskId)
+}
+
+// renderChangedTask renders the task the way the element the request targets
+// shows it, e.g. as a backlog card or a task list item.
+func (h *TaskHandler) renderChangedTask(c echo.Context, taskId string) error {
+	task, err := h.taskService.GetTask(taskId)
+	if err != nil {
 		if utils.IsNotFoundError(err) {
 			return echo.NewHTTPError(http.StatusNotFound, "fetching updated task", err)
 
 		} else {
 			return fmt.Errorf("fetching updated task: %w", err)
 		}
+	}
 
-	} else {
-		var project *model.Project
-		if task.ProjectID.Valid {
-			project, err = h.projectService.GetProject(task.ProjectID.ValueOrZero())
+	var project *model.Project
+	if task.ProjectID.Valid {
+		project, err = h.projectService.GetProject(task.ProjectID.ValueOrZero())
 
-			if err != nil {
-				if utils.IsNotFoundError(err) {
-					return echo.NewHTTPError(http.StatusNotFound, "getting project", err)
+		if err != nil {
+			if utils.IsNotFoundError(err) {
+				return echo.NewHTTPError(http.StatusNotFound, "getting project", err)
 
-				} else {
-					return fmt.Errorf("getting project: %w", err)
-				}
+			} else {
+				return fmt.Errorf("getting project: %w", err)
 			}
 		}
+	}
 
-		target := c.Request().Header.Get(htmx.HeaderTarget)
+	target := c.Request().Header.Get(htmx.HeaderTarget)
 
-		var taskTemplate templ.Component
-		if strings.HasPrefix(target, backlog.TaskSelector) {
-			taskTemplate = backlog.TaskCard(*task, project)
+	var taskTemplate templ.Component
+	if strings.HasPrefix(target, backlog.TaskSelector) {
+		taskTemplate = backlog.TaskCard(*task, project)
 
-		} else if strings.HasPrefix(target, tasklist.TaskSelector) {
-			taskTemplate = tasklist.TaskView(*task, project)
-		}
+	} else if strings.HasPrefix(target, tasklist.TaskSelector) {
+		taskTemplate = tasklist.TaskView(*task, project)
+
+	} else if strings.HasPrefix(target, pages.SubtaskSelector) {
+		taskTemplate = pages.SubtaskItem(*task)
 
-		if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, taskTemplate); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "rendering template", err)
+	} else if strings.HasPrefix(target, pages.ProjectTaskSelector) {
+		taskTemplate = pages.ProjectTaskItem(*task, project)
+
+	} else {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, taskTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "rendering template", err)
+	}
+
+	return nil
+}
+
+// bindCustomValues reads the values of the project's custom fields from the
+// submitted form and validates them. Values of fields the project doesn't
+// define are dropped.
+func bindCustomValues(c echo.Context, task *model.Task, project *model.Project) error {
+	values := map[string]string{}
+
+	if project != nil {
+		for _, field := range project.CustomFields {
+			if value := strings.TrimSpace(c.FormValue(field.InputName())); value != "" {
+				values[field.ID] = value
+			}
 		}
 
-		return nil
+		if err := project.ValidateCustomValues(values); err != nil {
+			return err
+		}
 	}
+
+	task.CustomValues = values
+
+	return nil
 }
 
-func (h *TaskHandler) handleTaskHide(c echo.Context) error {
-	defer c.Request().Body.Close()
+// publishTask brings the calendar event the task is published as in line with
+// the task. The change to the task stands even when the calendar can't be
+// updated, so failures are only logged.
+func (h *TaskHandler) publishTask(c echo.Context, taskId string) {
+	task, err := h.taskService.GetTask(taskId)
+	if err != nil {
+		slog.Warn("publishing task", "taskId", taskId, "error", err)
+		return
+	}
 
-	taskId := extractTaskId(c)
+	calendarID, eventID, err := h.calendarService.PublishTask(c.Request().Context(), task)
+	if err != nil {
+		slog.Warn("publishing task", "taskId", taskId, "error", err)
+	}
 
-	c.Logger().Debug("TaskHandler.handleTaskHide", "taskId", taskId)
+	if calendarID == task.EventCalendarID.String && eventID == task.EventID.String {
+		return
+	}
 
-	if err := h.taskService.HiddenToggleTask(taskId); err != nil {
-		if utils.IsNotFoundError(err) {
-			return echo.NewHTTPError(http.StatusNotFound, "updating task", err)
+	if err := h.taskService.LinkTaskEvent(taskId, calendarID, eventID); err != nil {
+		slog.Warn("linking task to its event", "taskId", taskId, "error", err)
+	}
+}
 
-		} else {
-			return fmt.Errorf("updating task: %w", err)
+// publishedTasks are the task and those of its subtasks which are published
+// as calendar events.
+func (h *TaskHandler) publishedTasks(taskId string) []model.Task {
+	var published []model.Task
+
+	if task, err := h.taskService.GetTask(taskId); err == nil && task.EventID.Valid {
+		published = append(published, *task)
+	}
+
+	if subtasks, err := h.taskService.GetSubtasks(taskId); err == nil {
+		for subtask := range subtasks.All() {
+			if subtask.EventID.Valid {
+				published = append(published, subtask)
+			}
 		}
 	}
 
-	return nil
+	return published
+}
+
+// budgetWarning tells when the scheduled task takes its project over the
+// weekly time budget of the week it's scheduled in.
+func (h *TaskHandler) budgetWarning(task *model.Task, projectsIndex *model.ProjectIndex) templ.Component {
+	if !task.ProjectID.Valid || !task.StartTime.Valid {
+		return templ.NopComponent
+	}
+
+	start, end := model.WeekOf(task.StartTime.Time)
+
+	tasks, err := h.taskService.GetTasksScheduledBetween(start, end)
+	if err != nil {
+		slog.Warn("checking budget", "taskId", task.ID, "error", err)
+		return templ.NopComponent
+	}
+
+	events, err := h.calendarService.GetEventsBetween(start, end)
+	if err != nil {
+		slog.Warn("checking budget without calendar events", "taskId", task.ID, "error", err)
+		events = model.NewEventList()
+	}
+
+	usages := model.BudgetUsages(projectsIndex, tasks, events)
+
+	project, usage, ok := model.ExceededBudget(projectsIndex, usages, task.ProjectID.String)
+	if !ok {
+		return templ.NopComponent
+	}
+
+	message := fmt.Sprintf("%s is %s over its weekly budget of %s",
+		project.Name,
+		strings.TrimSpace(utils.FormatDuration(-usage.Remaining())),
+		strings.TrimSpace(utils.FormatDuration(usage.Budget)))
+
+	return components.Encapsulate("div", "beforeend:body", components.WarningMessage(message))
+}
+
+// scheduleAlternatives offers the other free times the task could have been
+// fit into, in case the first doesn't suit.
+func scheduleAlternatives(task *model.Task, scheduled time.Time, alternatives []time.Time) templ.Component {
+	if len(alternatives) == 0 {
+		return templ.NopComponent
+	}
+
+	return components.Encapsulate("div", "beforeend:body", components.ScheduleAlternatives(task, scheduled, alternatives))
+}
+
+// firstFreeTime finds the first time from then on the task fits in between
+// meetings and the other scheduled tasks within working hours, along with the
+// next few alternatives to it. Without any it falls back to the next quarter
+// hour.
+func (h *TaskHandler) firstFreeTime(taskId string, from time.Time) (time.Time, []time.Time) {
+	nextSlot := roundUpToSlot(from)
+
+	task, err := h.taskService.GetTask(taskId)
+	if err != nil {
+		return nextSlot, nil
+	}
+
+	settings, err := h.settingsService.GetSettings()
+	if err != nil {
+		slog.Warn("scheduling without working hours", "taskId", taskId, "error", err)
+		return nextSlot, nil
+	}
+
+	until := from.AddDate(0, 0, schedulingHorizon)
+
+	tasks, err := h.taskService.GetTasksScheduledBetween(from, until)
+	if err != nil {
+		slog.Warn("scheduling without the other tasks", "taskId", taskId, "error", err)
+		tasks = model.NewTaskList()
+	}
+
+	events, err := h.calendarService.GetEventsBetween(from, until)
+	if err != nil {
+		slog.Warn("scheduling without calendar events", "taskId", taskId, "error", err)
+		events = model.NewEventList()
+	}
+
+	length := time.Duration(task.Duration.Int32) * time.Minute
+	slots := freeSlots(busyPeriods(tasks, events, taskId), from, length, settings.WorkingHours.OrDefault(), scheduleAlternativeCount+1)
+
+	if len(slots) == 0 {
+		return nextSlot, nil
+	}
+
+	return slots[0], slots[1:]
+}
+
+// preferredStartTime is the time of day the task's project prefers its tasks to
+// start at on the given date, if it has one.
+func (h *TaskHandler) preferredStartTime(taskId string, date time.Time) (time.Time, bool) {
+	task, err := h.taskService.GetTask(taskId)
+	if err != nil || !task.ProjectID.Valid {
+		return time.Time{}, false
+	}
+
+	project, err := h.projectService.GetProject(task.ProjectID.String)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return project.Defaults.On(date)
+}
+
+// bindMilestone drops the task's milestone unless it is one of its project's,
+// as happens when the task moves to another project.
+func bindMilestone(task *model.Task, project *model.Project) {
+	if project == nil {
+		task.MilestoneID = zero.String{}
+		return
+	}
+
+	if _, ok := project.Milestone(task.MilestoneID.String); !ok {
+		task.MilestoneID = zero.String{}
+	}
 }
@@ -0,0 +1,146 @@
+package task
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/guregu/null/v6"
+	"github.com/guregu/null/v6/zero"
+	"github.com/labstack/echo/v4"
+	"github.com/pleimann/camel-do/model"
+	"github.com/pleimann/camel-do/services/project"
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestDB(t *testing.T) *bolt.DB {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func newTestTaskService(t *testing.T, db *bolt.DB, workflow model.Workflow) *TaskService {
+	t.Helper()
+
+	taskService, err := NewTaskService(&TaskServiceConfig{Workflow: workflow}, db)
+	if err != nil {
+		t.Fatalf("NewTaskService() error = %v", err)
+	}
+
+	return taskService
+}
+
+func TestHandleTaskCompleteRejectsDisallowedTransition(t *testing.T) {
+	// Tasks have to be started before they can be done
+	workflow := model.Workflow{
+		model.Todo:       {model.InProgress},
+		model.InProgress: {model.Done},
+		model.Waiting:    {model.InProgress},
+		model.Done:       {model.Todo},
+		model.Cancelled:  {model.Todo},
+	}
+
+	taskService := newTestTaskService(t, newTestDB(t), workflow)
+
+	task := &model.Task{Title: zero.StringFrom("Not started")}
+	if err := taskService.AddTask(task); err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/tasks/"+task.ID+"/complete", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues(task.ID)
+
+	h := &TaskHandler{taskService: taskService}
+
+	var httpErr *echo.HTTPError
+	if err := h.handleTaskComplete(c); !errors.As(err, &httpErr) || httpErr.Code != http.StatusConflict {
+		t.Fatalf("handleTaskComplete() error = %v, want %d", err, http.StatusConflict)
+	}
+
+	stored, err := taskService.GetTask(task.ID)
+	if err != nil {
+		t.Fatalf("GetTask() error = %v", err)
+	}
+
+	if stored.Status != model.Todo {
+		t.Errorf("task status = %s, want it left at %s", stored.Status, model.Todo)
+	}
+}
+
+func TestHandleCreateTaskReminderOffset(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset string
+		want   null.Int32
+	}{
+		{name: "at the start is kept", offset: "0", want: null.Int32From(0)},
+		{name: "a time before is kept", offset: "30", want: null.Int32From(30)},
+		{name: "none picked takes the project's", offset: "", want: null.Int32From(15)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			taskService := newTestTaskService(t, db, nil)
+
+			projectService, err := project.NewProjectService(&project.ProjectServiceConfig{}, db)
+			if err != nil {
+				t.Fatalf("NewProjectService() error = %v", err)
+			}
+
+			err = projectService.AddProject(model.Project{
+				Name:     "Meetings",
+				Defaults: model.TaskDefaults{ReminderOffset: null.Int32From(15)},
+			})
+			if err != nil {
+				t.Fatalf("AddProject() error = %v", err)
+			}
+
+			projects, err := projectService.GetProjects()
+			if err != nil {
+				t.Fatalf("GetProjects() error = %v", err)
+			}
+
+			var projectID string
+			for _, project := range projects.All() {
+				projectID = project.ID
+			}
+
+			form := url.Values{"title": {"Standup notes"}, "projectId": {projectID}, "reminderOffset": {tt.offset}}
+
+			req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(form.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			h := &TaskHandler{taskService: taskService, projectService: projectService}
+			if err := h.handleCreateTask(c); err != nil {
+				t.Fatalf("handleCreateTask() error = %v", err)
+			}
+
+			tasks, err := taskService.GetProjectTasks(projectID)
+			if err != nil || tasks.Len() != 1 {
+				t.Fatalf("GetProjectTasks() = %v, %v, want the created task", tasks, err)
+			}
+
+			for task := range tasks.All() {
+				if task.ReminderOffset != tt.want {
+					t.Errorf("reminder offset = %v, want %v", task.ReminderOffset, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package task
+
+import (
+	"bytes"
+	"encoding/gob"
+	"log/slog"
+	"time"
+
+	"github.com/guregu/null/v6/zero"
+	"github.com/pleimann/camel-do/model"
+	bolt "go.etcd.io/bbolt"
+)
+
+// metaBucket holds markers for the migrations which already ran.
+var metaBucket = []byte("meta")
+
+var taskStatusMigrated = []byte("taskStatusMigrated")
+
+// legacyTaskFlags are the completion toggles tasks had before they got a
+// status. Gob matches fields by name so stored tasks decode into it.
+type legacyTaskFlags struct {
+	UpdatedAt time.Time
+	Completed zero.Bool
+	Hidden    zero.Bool
+}
+
+// migrateTaskStatus turns the Completed and Hidden toggles of stored tasks into
+// a status once: completed tasks are done and hidden ones cancelled.
+func migrateTaskStatus(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		if meta.Get(taskStatusMigrated) != nil {
+			return nil
+		}
+
+		if bucket := tx.Bucket([]byte("tasks")); bucket != nil {
+			// The bucket can't be modified while ForEach walks it
+			migrated := map[string][]byte{}
+
+			err := bucket.ForEach(func(taskID, taskBytes []byte) error {
+				flags := legacyTaskFlags{}
+				if err := gob.NewDecoder(bytes.NewReader(taskBytes)).Decode(&flags); err != nil {
+					return err
+				}
+
+				var status model.Status
+				switch {
+				case flags.Completed.Bool:
+					status = model.Done
+				case flags.Hidden.Bool:
+					status = model.Cancelled
+				default:
+					return nil
+				}
+
+				task := model.Task{}
+				if err := task.Unmarshal(taskBytes); err != nil {
+					return err
+				}
+
+				task.SetStatus(status, flags.UpdatedAt)
+
+				migratedBytes, err := task.Marshal()
+				if err != nil {
+					return err
+				}
+
+				migrated[string(taskID)] = migratedBytes
+
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+
+			for taskID, taskBytes := range migrated {
+				if err := bucket.Put([]byte(taskID), taskBytes); err != nil {
+					return err
+				}
+			}
+
+			slog.Info("migrated task status", "tasks", len(migrated))
+		}
+
+		return meta.Put(taskStatusMigrated, []byte(time.Now().Format(time.RFC3339)))
+	})
+}
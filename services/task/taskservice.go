@@ -3,6 +3,9 @@ package task
 import (
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/guregu/null/v6/zero"
@@ -13,6 +16,7 @@ import (
 )
 
 type TaskServiceConfig struct {
+	Workflow model.Workflow // Allowed status transitions, DefaultWorkflow if nil
 }
 
 // TaskService is a service for managing tasks.
@@ -22,18 +26,32 @@ type TaskService struct {
 }
 
 func NewTaskService(config *TaskServiceConfig, db *bolt.DB) (*TaskService, error) {
+	if config.Workflow == nil {
+		config.Workflow = model.DefaultWorkflow
+	}
+
 	taskService := &TaskService{
 		config: config,
 		db:     db,
 	}
 
+	if err := migrateTaskStatus(db); err != nil {
+		return nil, fmt.Errorf("migrating task status: %w", err)
+	}
+
 	return taskService, nil
 }
 
 func (t *TaskService) AddTask(task *model.Task) error {
+	return t.AddTaskWithSubtasks(task, nil)
+}
+
+// AddTaskWithSubtasks stores the task along with its subtasks in one
+// transaction, linking each subtask to the new task.
+func (t *TaskService) AddTaskWithSubtasks(task *model.Task, subtasks []model.Task) error {
 	task.ID = ulid.Make().String()
 
-	slog.Debug("TaskService.AddTask", "task", task)
+	slog.Debug("TaskService.AddTask", "task", task, "subtasks", len(subtasks))
 
 	err := t.db.Update(func(tx *bolt.Tx) error {
 		bucket, err := tx.CreateBucketIfNotExists([]byte("tasks"))
@@ -45,13 +63,18 @@ func (t *TaskService) AddTask(task *model.Task) error {
 			return fmt.Errorf("tasks bucket does not exist")
 		}
 
-		taskBytes, err := task.Marshal()
-
-		if err != nil {
+		if err := putNewTask(tx, bucket, task); err != nil {
 			return err
 		}
 
-		bucket.Put([]byte(task.ID), taskBytes)
+		for i := range subtasks {
+			subtasks[i].ID = ulid.Make().String()
+			subtasks[i].ParentID = zero.StringFrom(task.ID)
+
+			if err := putNewTask(tx, bucket, &subtasks[i]); err != nil {
+				return err
+			}
+		}
 
 		return nil
 	})
@@ -63,6 +86,22 @@ func (t *TaskService) AddTask(task *model.Task) error {
 	return nil
 }
 
+func putNewTask(tx *bolt.Tx, bucket *bolt.Bucket, task *model.Task) error {
+	taskBytes, err := task.Marshal()
+
+	if err != nil {
+		return err
+	}
+
+	if err := bucket.Put([]byte(task.ID), taskBytes); err != nil {
+		return err
+	}
+
+	_, err = recordActivity(tx, model.Activity{TaskID: task.ID, Kind: model.CreatedActivity})
+
+	return err
+}
+
 func (t *TaskService) GetTask(id string) (*model.Task, error) {
 	slog.Debug("TaskService.GetTask", "id", id)
 
@@ -94,51 +133,75 @@ func (t *TaskService) GetTask(id string) (*model.Task, error) {
 	return &task, nil
 }
 
+// CompleteToggleTask marks an open task as done and reopens a closed one.
 func (t *TaskService) CompleteToggleTask(id string) error {
 	slog.Debug("TaskService.CompleteToggleTask", "id", id)
 
-	err := t.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte("tasks"))
-
-		if bucket == nil {
-			return fmt.Errorf("tasks bucket does not exist")
-		}
-
-		taskBytes := bucket.Get([]byte(id))
-
-		if taskBytes == nil {
-			return utils.NewNotFoundError("task", id)
+	err := t.updateTask(id, func(tx *bolt.Tx, task *model.Task) error {
+		status := model.Done
+		if task.Status.IsClosed() {
+			status = model.Todo
 		}
 
-		task := model.Task{}
-
-		if err := task.Unmarshal(taskBytes); err != nil {
-			return err
-		}
+		return t.changeStatus(tx, task, status)
+	})
 
-		task.Completed.SetValid(!task.Completed.ValueOr(false))
+	if err != nil {
+		return fmt.Errorf("TaskService.CompleteToggleTask (%s): %w", id, err)
+	}
 
-		if taskBytes, err := task.Marshal(); err != nil {
-			return err
+	return nil
+}
 
-		} else {
-			bucket.Put([]byte(id), taskBytes)
-		}
+// SetTaskStatus moves the task to the status if the workflow allows it.
+func (t *TaskService) SetTaskStatus(id string, status model.Status) error {
+	slog.Debug("TaskService.SetTaskStatus", "id", id, "status", status)
 
-		return nil
+	err := t.updateTask(id, func(tx *bolt.Tx, task *model.Task) error {
+		return t.changeStatus(tx, task, status)
 	})
 
 	if err != nil {
-		return fmt.Errorf("TaskService.CompleteToggleTask (%s): %w", id, err)
+		return fmt.Errorf("TaskService.SetTaskStatus (%s): %w", id, err)
 	}
 
 	return nil
 }
 
-func (t *TaskService) HiddenToggleTask(id string) error {
-	slog.Debug("TaskService.CompleteToggleTask", "id", id)
+// Workflow is the set of status transitions tasks are allowed to make.
+func (t *TaskService) Workflow() model.Workflow {
+	return t.config.Workflow
+}
 
-	err := t.db.Update(func(tx *bolt.Tx) error {
+func (t *TaskService) changeStatus(tx *bolt.Tx, task *model.Task, status model.Status) error {
+	if task.Status == status {
+		return nil
+	}
+
+	if !t.config.Workflow.Allows(task.Status, status) {
+		return &model.TransitionError{From: task.Status, To: status}
+	}
+
+	previous := task.Status
+	task.SetStatus(status, time.Now())
+
+	activity := model.Activity{TaskID: task.ID, Kind: model.StatusChangedActivity, From: previous.String(), To: status.String()}
+	if status == model.Done {
+		activity = model.Activity{TaskID: task.ID, Kind: model.CompletedActivity}
+
+	} else if previous == model.Done && status == model.Todo {
+		activity = model.Activity{TaskID: task.ID, Kind: model.ReopenedActivity}
+	}
+
+	_, err := recordActivity(tx, activity)
+
+	return err
+}
+
+// updateTask loads the task, lets update change it and stores it again in one
+// transaction.
+func (t *TaskService) updateTask(id string, update func(tx *bolt.Tx, task *model.Task) error) error {
+	return t.db.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte("tasks"))
 
 		if bucket == nil {
@@ -157,23 +220,19 @@ func (t *TaskService) HiddenToggleTask(id string) error {
 			return err
 		}
 
-		task.Hidden.SetValid(!task.Hidden.ValueOr(false))
-
-		if taskBytes, err := task.Marshal(); err != nil {
+		if err := update(tx, &task); err != nil {
 			return err
-
-		} else {
-			bucket.Put([]byte(id), taskBytes)
 		}
 
-		return nil
-	})
+		task.UpdatedAt = time.Now()
 
-	if err != nil {
-		return fmt.Errorf("TaskService.HiddenToggleTask (%s): %w", id, err)
-	}
+		taskBytes, err := task.Marshal()
+		if err != nil {
+			return err
+		}
 
-	return nil
+		return bucket.Put([]byte(id), taskBytes)
+	})
 }
 
 func (t *TaskService) UpdateTask(task *model.Task) error {
@@ -186,6 +245,13 @@ func (t *TaskService) UpdateTask(task *model.Task) error {
 			return fmt.Errorf("tasks bucket does not exist")
 		}
 
+		previous := model.Task{}
+		if previousBytes := bucket.Get([]byte(task.ID)); previousBytes != nil {
+			if err := previous.Unmarshal(previousBytes); err != nil {
+				return err
+			}
+		}
+
 		taskBytes, err := task.Marshal()
 
 		if err != nil {
@@ -194,7 +260,16 @@ func (t *TaskService) UpdateTask(task *model.Task) error {
 
 		bucket.Put([]byte(task.ID), taskBytes)
 
-		return nil
+		if previous.ProjectID.String != task.ProjectID.String {
+			_, err = recordActivity(tx, model.Activity{
+				TaskID: task.ID,
+				Kind:   model.MovedProjectActivity,
+				From:   previous.ProjectID.String,
+				To:     task.ProjectID.String,
+			})
+		}
+
+		return err
 	})
 
 	if err != nil {
@@ -223,6 +298,8 @@ func (t *TaskService) ScheduleTask(id string, time zero.Time) error {
 		}
 
 		task.StartTime = time
+		task.AllDay = zero.BoolFrom(false)
+		task.EndTime = zero.TimeFromPtr(nil)
 
 		if taskBytes, err := task.Marshal(); err != nil {
 			return err
@@ -231,7 +308,15 @@ func (t *TaskService) ScheduleTask(id string, time zero.Time) error {
 			bucket.Put([]byte(id), taskBytes)
 		}
 
-		return nil
+		activity := model.Activity{TaskID: id, Kind: model.UnscheduledActivity}
+		if time.Valid {
+			activity.Kind = model.ScheduledActivity
+			activity.To = time.Time.Format(model.ActivityTimeFormat)
+		}
+
+		_, err := recordActivity(tx, activity)
+
+		return err
 	})
 
 	if err != nil {
@@ -241,6 +326,80 @@ func (t *TaskService) ScheduleTask(id string, time zero.Time) error {
 	return nil
 }
 
+// ScheduleTaskAllDay schedules a task for whole days, from the day start falls
+// on up to but excluding the day end falls on, in the user's time zone.
+func (t *TaskService) ScheduleTaskAllDay(id string, start time.Time, end time.Time) error {
+	slog.Debug("TaskService.ScheduleTaskAllDay", "taskId", id, "start", start, "end", end)
+
+	startDate := utils.StartOfDay(start)
+	endDate := utils.StartOfDay(end)
+
+	if !endDate.After(startDate) {
+		endDate = startDate.AddDate(0, 0, 1)
+	}
+
+	err := t.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("tasks"))
+
+		taskBytes := bucket.Get([]byte(id))
+
+		if taskBytes == nil {
+			return utils.NewNotFoundError("task", id)
+		}
+
+		task := model.Task{}
+
+		if err := task.Unmarshal(taskBytes); err != nil {
+			return err
+		}
+
+		task.StartTime = zero.TimeFrom(startDate)
+		task.EndTime = zero.TimeFrom(endDate)
+		task.AllDay = zero.BoolFrom(true)
+
+		if taskBytes, err := task.Marshal(); err != nil {
+			return err
+
+		} else {
+			bucket.Put([]byte(id), taskBytes)
+		}
+
+		_, err := recordActivity(tx, model.Activity{
+			TaskID: id,
+			Kind:   model.ScheduledAllDayActivity,
+			From:   startDate.Format(model.ActivityTimeFormat),
+			To:     endDate.Format(model.ActivityTimeFormat),
+		})
+
+		return err
+	})
+
+	if err != nil {
+		return fmt.Errorf("TaskService.ScheduleTaskAllDay(%s): %w", id, err)
+	}
+
+	return nil
+}
+
+// LinkTaskEvent records the calendar event the task is published as, or that
+// it has none when the IDs are empty.
+func (t *TaskService) LinkTaskEvent(id string, calendarID string, eventID string) error {
+	slog.Debug("TaskService.LinkTaskEvent", "id", id, "calendarId", calendarID, "eventId", eventID)
+
+	err := t.updateTask(id, func(tx *bolt.Tx, task *model.Task) error {
+		task.EventCalendarID = zero.StringFrom(calendarID)
+		task.EventID = zero.StringFrom(eventID)
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("TaskService.LinkTaskEvent (%s): %w", id, err)
+	}
+
+	return nil
+}
+
 func (t *TaskService) DeleteTask(id string) error {
 	slog.Debug("TaskService.DeleteTask", "id", id)
 
@@ -252,10 +411,37 @@ func (t *TaskService) DeleteTask(id string) error {
 			return utils.NewNotFoundError("task", id)
 		}
 
-		if err := bucket.Delete([]byte(id)); err != nil {
+		// Subtasks go along with their parent
+		ids := [][]byte{[]byte(id)}
+
+		err := bucket.ForEach(func(taskID, taskBytes []byte) error {
+			task := model.Task{}
+
+			if err := task.Unmarshal(taskBytes); err != nil {
+				return err
+			}
+
+			if task.ParentID.String == id {
+				ids = append(ids, taskID)
+			}
+
+			return nil
+		})
+
+		if err != nil {
 			return err
 		}
 
+		for _, taskID := range ids {
+			if err := bucket.Delete(taskID); err != nil {
+				return err
+			}
+
+			if err := deleteActivity(tx, string(taskID)); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
 
@@ -285,7 +471,8 @@ func (t *TaskService) GetBacklogTasks() (*model.TaskList, error) {
 				return err
 			}
 
-			if task.StartTime.IsZero() {
+			// Subtasks are listed with their parent rather than in the backlog
+			if task.StartTime.IsZero() && !task.ParentID.Valid {
 				taskList.Push(task)
 			}
 
@@ -311,25 +498,27 @@ func (t *TaskService) GetBacklogTasks() (*model.TaskList, error) {
 func (t *TaskService) GetTodaysTasks() (*model.TaskList, error) {
 	slog.Debug("TaskService.GetTodaysTasks")
 
-	year, month, day := time.Now().Date()
-
-	date := time.Date(year, month, day, 0, 0, 0, 0, time.Now().Location())
-
-	return t.GetTasksScheduledOnDate(date)
+	return t.GetTasksScheduledOnDate(utils.Now())
 }
 
+// GetTasksScheduledOnDate returns the tasks scheduled on the day of date in the
+// user's time zone.
 func (t *TaskService) GetTasksScheduledOnDate(date time.Time) (*model.TaskList, error) {
 	slog.Debug("TaskService.GetTasksScheduledOnDate")
 
-	year, month, day := date.Date()
+	beginningOfDay := utils.StartOfDay(date)
 
-	beginningOfDay := time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	endOfDay := beginningOfDay.AddDate(0, 0, 1)
 
-	endOfDay := beginningOfDay.Add(time.Hour * 24)
+	return t.GetTasksScheduledBetween(beginningOfDay, endOfDay)
+}
 
+// GetTasksScheduledBetween returns the tasks scheduled to cover any time in
+// [start, end).
+func (t *TaskService) GetTasksScheduledBetween(start time.Time, end time.Time) (*model.TaskList, error) {
 	taskList := model.NewTaskList()
 
-	slog.Debug("finding tasks between", "start", beginningOfDay, "end", endOfDay)
+	slog.Debug("finding tasks between", "start", start, "end", end)
 
 	err := t.db.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte("tasks"))
@@ -345,11 +534,9 @@ func (t *TaskService) GetTasksScheduledOnDate(date time.Time) (*model.TaskList,
 				return err
 			}
 
-			if task.StartTime.Valid &&
-				task.StartTime.Time.Equal(beginningOfDay) ||
-				(task.StartTime.Time.After(beginningOfDay) &&
-					task.StartTime.Time.Before(endOfDay)) {
-				slog.Debug("task", "id", task.ID, "startTime", task.StartTime, "start", beginningOfDay, "end", endOfDay)
+			// Tasks spanning several days appear on each day they cover
+			if task.Overlaps(start, end) {
+				slog.Debug("task", "id", task.ID, "startTime", task.StartTime, "start", start, "end", end)
 				taskList.Push(task)
 			}
 
@@ -364,10 +551,250 @@ func (t *TaskService) GetTasksScheduledOnDate(date time.Time) (*model.TaskList,
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("TaskService.GetTasksScheduledOnDate (%s - %s): %w", beginningOfDay, endOfDay, err)
+		return nil, fmt.Errorf("TaskService.GetTasksScheduledBetween (%s - %s): %w", start, end, err)
 	}
 
-	slog.Debug("found tasks", "count", taskList.Len(), "start", beginningOfDay, "end", endOfDay)
+	slog.Debug("found tasks", "count", taskList.Len(), "start", start, "end", end)
+
+	return taskList, nil
+}
+
+// GetSubtasks returns the subtasks of the task in the order they were added.
+func (t *TaskService) GetSubtasks(parentID string) (*model.TaskList, error) {
+	slog.Debug("TaskService.GetSubtasks", "parentId", parentID)
+
+	taskList := model.NewTaskList()
+
+	err := t.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("tasks"))
+
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(taskID, taskBytes []byte) error {
+			task := model.Task{}
+
+			if err := task.Unmarshal(taskBytes); err != nil {
+				return err
+			}
+
+			if task.ParentID.String == parentID {
+				taskList.Push(task)
+			}
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("TaskService.GetSubtasks (%s): %w", parentID, err)
+	}
+
+	taskList.Sort()
+
+	return taskList, nil
+}
+
+// ReassignProjectTasks moves every task of a project to another one, or out of
+// any project when to has no ID. Milestones and the values of fields the other
+// project doesn't have are dropped. It's done in tx, so the tasks move along
+// with whatever else tx changes or not at all.
+func (t *TaskService) ReassignProjectTasks(tx *bolt.Tx, fromProjectID string, to *model.Project) error {
+	slog.Debug("TaskService.ReassignProjectTasks", "from", fromProjectID, "to", to.ID)
+
+	bucket := tx.Bucket([]byte("tasks"))
+
+	if bucket == nil {
+		return nil
+	}
+
+	// The bucket can't be modified while ForEach walks it
+	var moved []model.Task
+
+	err := bucket.ForEach(func(taskID, taskBytes []byte) error {
+		task := model.Task{}
+
+		if err := task.Unmarshal(taskBytes); err != nil {
+			return err
+		}
+
+		if task.ProjectID.String == fromProjectID {
+			moved = append(moved, task)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, task := range moved {
+		task.ProjectID = zero.StringFrom(to.ID)
+		task.MilestoneID = zero.String{}
+		task.UpdatedAt = time.Now()
+
+		for fieldID := range task.CustomValues {
+			if _, ok := to.CustomField(fieldID); !ok {
+				delete(task.CustomValues, fieldID)
+			}
+		}
+
+		taskBytes, err := task.Marshal()
+		if err != nil {
+			return err
+		}
+
+		if err := bucket.Put([]byte(task.ID), taskBytes); err != nil {
+			return err
+		}
+
+		_, err = recordActivity(tx, model.Activity{
+			TaskID: task.ID,
+			Kind:   model.MovedProjectActivity,
+			From:   fromProjectID,
+			To:     to.ID,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetProjectTasks returns every task of the project, scheduled or not.
+func (t *TaskService) GetProjectTasks(projectID string) (*model.TaskList, error) {
+	slog.Debug("TaskService.GetProjectTasks", "projectId", projectID)
+
+	tasks, err := t.GetAllTasks()
+	if err != nil {
+		return nil, fmt.Errorf("TaskService.GetProjectTasks (%s): %w", projectID, err)
+	}
+
+	return tasks.Filter(func(task model.Task) bool {
+		return task.ProjectID.String == projectID
+	}), nil
+}
+
+// GetEventTasks returns the tasks created to follow up on the calendar event.
+func (t *TaskService) GetEventTasks(eventID string) (*model.TaskList, error) {
+	slog.Debug("TaskService.GetEventTasks", "eventId", eventID)
+
+	tasks, err := t.GetAllTasks()
+	if err != nil {
+		return nil, fmt.Errorf("TaskService.GetEventTasks (%s): %w", eventID, err)
+	}
+
+	return tasks.Filter(func(task model.Task) bool {
+		return task.SourceEventID.String == eventID
+	}), nil
+}
+
+// GetAllTasks returns every task, scheduled or not.
+func (t *TaskService) GetAllTasks() (*model.TaskList, error) {
+	slog.Debug("TaskService.GetAllTasks")
+
+	taskList := model.NewTaskList()
+
+	err := t.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("tasks"))
+
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(taskID, taskBytes []byte) error {
+			task := model.Task{}
+
+			if err := task.Unmarshal(taskBytes); err != nil {
+				return err
+			}
+
+			taskList.Push(task)
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("TaskService.GetAllTasks: %w", err)
+	}
+
+	taskList.Sort()
+
+	return taskList, nil
+}
+
+// SearchTasks finds tasks whose title, description, custom field values or
+// comments contain the query, ignoring case.
+func (t *TaskService) SearchTasks(query string) (*model.TaskList, error) {
+	slog.Debug("TaskService.SearchTasks", "query", query)
+
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	taskList := model.NewTaskList()
+
+	if query == "" {
+		return taskList, nil
+	}
+
+	contains := func(s string) bool {
+		return strings.Contains(strings.ToLower(s), query)
+	}
+
+	err := t.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("tasks"))
+
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(taskID, taskBytes []byte) error {
+			task := model.Task{}
+
+			if err := task.Unmarshal(taskBytes); err != nil {
+				return err
+			}
+
+			matches := contains(task.Title.String) || contains(task.Description.String) ||
+				slices.ContainsFunc(slices.Collect(maps.Values(task.CustomValues)), contains)
+
+			if !matches {
+				if activity := taskActivityBucket(tx, task.ID); activity != nil {
+					err := activity.ForEach(func(activityID, activityBytes []byte) error {
+						comment := model.Activity{}
+
+						if err := comment.Unmarshal(activityBytes); err != nil {
+							return err
+						}
+
+						matches = matches || comment.IsComment() && contains(comment.Body)
+
+						return nil
+					})
+
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			if matches {
+				taskList.Push(task)
+			}
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("TaskService.SearchTasks (%s): %w", query, err)
+	}
+
+	taskList.Sort()
 
 	return taskList, nil
 }
@@ -66,11 +66,16 @@ func (t *TaskSyncService) GetGoogleTasks() []model.Task {
 		for _, gtask := range gtasks.Items {
 			order, _ := strconv.ParseInt(gtask.Position, 10, 32)
 
+			status := model.Todo
+			if gtask.Completed != nil {
+				status = model.Done
+			}
+
 			modelTasks = append(modelTasks, model.Task{
 				GTaskID:     zero.StringFrom(gtask.Id),
 				Title:       zero.StringFrom(gtask.Title),
 				Description: zero.StringFrom(gtask.Notes),
-				Completed:   zero.BoolFrom(gtask.Completed != nil),
+				Status:      status,
 				Rank:        zero.Int32From(int32(order)),
 			})
 		}
@@ -0,0 +1,315 @@
+package tasktemplate
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/angelofallars/htmx-go"
+	"github.com/guregu/null/v6/zero"
+	"github.com/labstack/echo/v4"
+
+	"github.com/pleimann/camel-do/model"
+	"github.com/pleimann/camel-do/services/project"
+	"github.com/pleimann/camel-do/templates/blocks/backlog"
+	"github.com/pleimann/camel-do/templates/components"
+	"github.com/pleimann/camel-do/templates/pages"
+	"github.com/pleimann/camel-do/utils"
+)
+
+type TemplateHandler struct {
+	*echo.Group
+	templateService *TemplateService
+	taskService     TaskService
+	projectService  *project.ProjectService
+}
+
+// TaskService interface to avoid circular dependencies
+type TaskService interface {
+	GetTask(id string) (*model.Task, error)
+	GetSubtasks(parentID string) (*model.TaskList, error)
+	AddTaskWithSubtasks(task *model.Task, subtasks []model.Task) error
+}
+
+func NewTemplateHandler(
+	group *echo.Group,
+	templateService *TemplateService,
+	taskService TaskService,
+	projectService *project.ProjectService,
+) *TemplateHandler {
+	templateHandler := &TemplateHandler{
+		Group:           group,
+		templateService: templateService,
+		taskService:     taskService,
+		projectService:  projectService,
+	}
+
+	group.GET("/list", templateHandler.handleListTemplates).Name = "list-templates"
+	group.GET("/new", templateHandler.handleNewTemplate).Name = "new-template"
+	group.GET("/edit/:id", templateHandler.handleEditTemplate).Name = "edit-template"
+
+	group.POST("", templateHandler.handleTemplateCreate).Name = "create-template"
+	group.POST("/from-task/:taskId", templateHandler.handleTemplateFromTask).Name = "create-template-from-task"
+	group.PUT("/:id", templateHandler.handleTemplateUpdate).Name = "update-template"
+	group.DELETE("/:id", templateHandler.handleTemplateDelete).Name = "delete-template"
+
+	group.GET("/:id/use", templateHandler.handleUseTemplateDialog).Name = "use-template-dialog"
+	group.POST("/:id/use", templateHandler.handleUseTemplate).Name = "use-template"
+
+	return templateHandler
+}
+
+func (h *TemplateHandler) handleListTemplates(c echo.Context) error {
+	templates, err := h.templateService.GetTemplates()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting templates", err)
+	}
+
+	projectsIndex, err := h.projectService.GetProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	dialogTemplate := components.Dialog(pages.TemplateList(templates, projectsIndex))
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, dialogTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+func (h *TemplateHandler) handleNewTemplate(c echo.Context) error {
+	return h.renderTemplateDialog(c, nil)
+}
+
+func (h *TemplateHandler) handleEditTemplate(c echo.Context) error {
+	taskTemplate, err := h.templateService.GetTemplate(c.Param("id"))
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "getting template", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting template", err)
+		}
+	}
+
+	return h.renderTemplateDialog(c, taskTemplate)
+}
+
+func (h *TemplateHandler) handleTemplateCreate(c echo.Context) error {
+	taskTemplate := model.TaskTemplate{}
+
+	if err := bindTemplate(c, &taskTemplate); err != nil {
+		return err
+	}
+
+	c.Logger().Debug("TemplateHandler.handleTemplateCreate", "template", taskTemplate)
+
+	if err := h.templateService.AddTemplate(&taskTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "adding template", err)
+	}
+
+	return htmx.NewResponse().
+		AddTrigger(htmx.Trigger("close-modal")).
+		Write(c.Response().Writer)
+}
+
+// handleTemplateFromTask saves a task and its subtasks as a new template and
+// opens it for editing.
+func (h *TemplateHandler) handleTemplateFromTask(c echo.Context) error {
+	taskId := c.Param("taskId")
+
+	task, err := h.taskService.GetTask(taskId)
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "getting task", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting task", err)
+		}
+	}
+
+	subtasks, err := h.taskService.GetSubtasks(taskId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting subtasks", err)
+	}
+
+	taskTemplate := model.NewTaskTemplateFromTask(*task, subtasks)
+
+	c.Logger().Debug("TemplateHandler.handleTemplateFromTask", "template", taskTemplate)
+
+	if err := h.templateService.AddTemplate(&taskTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "adding template", err)
+	}
+
+	return h.renderTemplateDialog(c, &taskTemplate)
+}
+
+func (h *TemplateHandler) handleTemplateUpdate(c echo.Context) error {
+	id := c.Param("id")
+
+	taskTemplate := model.TaskTemplate{}
+
+	if err := bindTemplate(c, &taskTemplate); err != nil {
+		return err
+	}
+
+	c.Logger().Debug("TemplateHandler.handleTemplateUpdate", "template", taskTemplate)
+
+	if err := h.templateService.UpdateTemplate(id, taskTemplate); err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "updating template", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "updating template", err)
+		}
+	}
+
+	return htmx.NewResponse().
+		AddTrigger(htmx.Trigger("close-modal")).
+		Write(c.Response().Writer)
+}
+
+func (h *TemplateHandler) handleTemplateDelete(c echo.Context) error {
+	id := c.Param("id")
+
+	c.Logger().Debug("TemplateHandler.handleTemplateDelete", "templateId", id)
+
+	if err := h.templateService.DeleteTemplate(id); err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "deleting template", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "deleting template", err)
+		}
+	}
+
+	return nil
+}
+
+func (h *TemplateHandler) handleUseTemplateDialog(c echo.Context) error {
+	taskTemplate, err := h.templateService.GetTemplate(c.Param("id"))
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "getting template", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting template", err)
+		}
+	}
+
+	projectsIndex, err := h.projectService.GetProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	useTemplateDialog := pages.UseTemplateDialog(taskTemplate, projectsIndex.Get(taskTemplate.ProjectID.String))
+
+	dialogTemplate := components.Dialog(useTemplateDialog)
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, dialogTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// handleUseTemplate creates a task and its subtasks from the template, filling
+// in the prompted variables, and adds it to the backlog.
+func (h *TemplateHandler) handleUseTemplate(c echo.Context) error {
+	taskTemplate, err := h.templateService.GetTemplate(c.Param("id"))
+	if err != nil {
+		if utils.IsNotFoundError(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "getting template", err)
+
+		} else {
+			return echo.NewHTTPError(http.StatusInternalServerError, "getting template", err)
+		}
+	}
+
+	values := map[string]string{}
+	for _, variable := range taskTemplate.Variables() {
+		value := strings.TrimSpace(c.FormValue(pages.TemplateVariableInputPrefix + variable))
+		if value == "" {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("%s is required", variable))
+		}
+
+		values[variable] = value
+	}
+
+	task, subtasks := taskTemplate.Instantiate(values, utils.Now())
+
+	var project *model.Project
+	if task.ProjectID.Valid {
+		if project, err = h.projectService.GetProject(task.ProjectID.String); err != nil {
+			if utils.IsNotFoundError(err) {
+				// The project was deleted since the template was saved
+				task.ProjectID = zero.String{}
+
+				for i := range subtasks {
+					subtasks[i].ProjectID = task.ProjectID
+				}
+
+			} else {
+				return echo.NewHTTPError(http.StatusInternalServerError, "getting project", err)
+			}
+		}
+	}
+
+	c.Logger().Debug("TemplateHandler.handleUseTemplate", "task", task, "subtasks", len(subtasks))
+
+	if err := h.taskService.AddTaskWithSubtasks(&task, subtasks); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "adding task", err)
+	}
+
+	addedTaskTemplate := backlog.TaskCard(task, project)
+
+	if err := htmx.NewResponse().
+		AddTrigger(htmx.Trigger("close-modal")).
+		Retarget("#"+backlog.Selector).
+		Reswap(htmx.SwapAfterBegin).
+		RenderTempl(c.Request().Context(), c.Response().Writer, addedTaskTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+func (h *TemplateHandler) renderTemplateDialog(c echo.Context, taskTemplate *model.TaskTemplate) error {
+	projectsIndex, err := h.projectService.GetProjects()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
+	}
+
+	dialogTemplate := components.Dialog(pages.TemplateDialog(projectsIndex, taskTemplate))
+
+	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, dialogTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
+	}
+
+	return nil
+}
+
+// bindTemplate reads the template form, taking the subtasks one per line.
+func bindTemplate(c echo.Context, taskTemplate *model.TaskTemplate) error {
+	if err := c.Bind(taskTemplate); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "decoding form data", err)
+	}
+
+	taskTemplate.Name = strings.TrimSpace(taskTemplate.Name)
+	taskTemplate.TitlePattern = strings.TrimSpace(taskTemplate.TitlePattern)
+
+	if taskTemplate.Name == "" || taskTemplate.TitlePattern == "" {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "a template needs a name and a task title")
+	}
+
+	taskTemplate.Subtasks = nil
+	for line := range strings.Lines(c.FormValue("subtasks")) {
+		if subtask := strings.TrimSpace(line); subtask != "" {
+			taskTemplate.Subtasks = append(taskTemplate.Subtasks, subtask)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package tasktemplate
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/pleimann/camel-do/model"
+	"github.com/pleimann/camel-do/utils"
+	bolt "go.etcd.io/bbolt"
+)
+
+var templatesBucket = []byte("templates")
+
+type TemplateServiceConfig struct {
+}
+
+// TemplateService is a service for managing the templates tasks are created
+// from.
+type TemplateService struct {
+	config *TemplateServiceConfig
+	db     *bolt.DB
+}
+
+func NewTemplateService(config *TemplateServiceConfig, db *bolt.DB) (*TemplateService, error) {
+	templateService := &TemplateService{
+		config: config,
+		db:     db,
+	}
+
+	return templateService, nil
+}
+
+func (s *TemplateService) GetTemplate(id string) (*model.TaskTemplate, error) {
+	slog.Debug("TemplateService.GetTemplate", "id", id)
+
+	taskTemplate := model.TaskTemplate{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(templatesBucket)
+
+		if bucket == nil {
+			return utils.NewNotFoundError("template", id)
+		}
+
+		templateBytes := bucket.Get([]byte(id))
+
+		if templateBytes == nil {
+			return utils.NewNotFoundError("template", id)
+		}
+
+		return taskTemplate.Unmarshal(templateBytes)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("fetching template %s %w", id, err)
+	}
+
+	return &taskTemplate, nil
+}
+
+func (s *TemplateService) GetTemplates() (*model.TaskTemplateIndex, error) {
+	slog.Debug("TemplateService.GetTemplates")
+
+	templateIndex := model.NewTaskTemplateIndex()
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(templatesBucket)
+
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, templateBytes []byte) error {
+			taskTemplate := model.TaskTemplate{}
+
+			if err := taskTemplate.Unmarshal(templateBytes); err != nil {
+				return err
+			}
+
+			templateIndex.Add(taskTemplate)
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("fetching all templates %w", err)
+	}
+
+	return templateIndex, nil
+}
+
+func (s *TemplateService) AddTemplate(taskTemplate *model.TaskTemplate) error {
+	taskTemplate.ID = ulid.Make().String()
+	taskTemplate.CreatedAt = time.Now()
+	taskTemplate.UpdatedAt = taskTemplate.CreatedAt
+
+	slog.Debug("TemplateService.AddTemplate", "template", taskTemplate)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(templatesBucket)
+		if err != nil {
+			return err
+		}
+
+		templateBytes, err := taskTemplate.Marshal()
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(taskTemplate.ID), templateBytes)
+	})
+
+	if err != nil {
+		return fmt.Errorf("TemplateService.AddTemplate (%s): %w", taskTemplate.Name, err)
+	}
+
+	return nil
+}
+
+func (s *TemplateService) UpdateTemplate(id string, taskTemplate model.TaskTemplate) error {
+	slog.Debug("TemplateService.UpdateTemplate", "template", taskTemplate)
+
+	taskTemplate.ID = id
+	taskTemplate.UpdatedAt = time.Now()
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(templatesBucket)
+
+		if bucket == nil {
+			return utils.NewNotFoundError("template", id)
+		}
+
+		existingBytes := bucket.Get([]byte(id))
+		if existingBytes == nil {
+			return utils.NewNotFoundError("template", id)
+		}
+
+		existing := model.TaskTemplate{}
+		if err := existing.Unmarshal(existingBytes); err != nil {
+			return err
+		}
+
+		taskTemplate.CreatedAt = existing.CreatedAt
+
+		templateBytes, err := taskTemplate.Marshal()
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(id), templateBytes)
+	})
+
+	if err != nil {
+		return fmt.Errorf("TemplateService.UpdateTemplate (%s): %w", id, err)
+	}
+
+	return nil
+}
+
+func (s *TemplateService) DeleteTemplate(id string) error {
+	slog.Debug("TemplateService.DeleteTemplate", "id", id)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(templatesBucket)
+
+		if bucket == nil || bucket.Get([]byte(id)) == nil {
+			return utils.NewNotFoundError("template", id)
+		}
+
+		return bucket.Delete([]byte(id))
+	})
+
+	if err != nil {
+		return fmt.Errorf("TemplateService.DeleteTemplate (%s): %w", id, err)
+	}
+
+	return nil
+}
@@ -7,10 +7,12 @@ import (
 	"github.com/angelofallars/htmx-go"
 	"github.com/labstack/echo/v4"
 
+	"github.com/pleimann/camel-do/model"
 	"github.com/pleimann/camel-do/services/cal"
 	"github.com/pleimann/camel-do/services/project"
 	"github.com/pleimann/camel-do/services/task"
 	"github.com/pleimann/camel-do/templates/blocks/timeline"
+	"github.com/pleimann/camel-do/utils"
 )
 
 type TimelineHandler struct {
@@ -39,11 +41,11 @@ func NewTaskHandler(
 }
 
 func (h *TimelineHandler) handleGetTimeline(c echo.Context) error {
-	var date time.Time = time.Now()
+	var date time.Time = utils.Now()
 	if c.QueryParams().Has("date") {
 		dateString := c.QueryParam("date")
 
-		if d, err := time.ParseInLocation("20060102", dateString, time.Local); err != nil {
+		if d, err := utils.ParseDate("20060102", dateString); err != nil {
 			return c.String(http.StatusBadRequest, "invalid date `"+dateString+"`")
 
 		} else {
@@ -57,19 +59,26 @@ func (h *TimelineHandler) handleGetTimeline(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "getting tasks", err)
 	}
 
-	events, err := h.calendarService.GetTodaysEvents()
+	startOfWeek, _ := model.WeekOf(utils.Now())
+	weekEvents, err := h.calendarService.GetEventsByDay(startOfWeek, 7)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "getting the week's events", err)
+	}
+
+	events, err := h.calendarService.GetEventsOnDate(date)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "getting events", err)
 	}
 
-	projectsIndex, err := h.projectService.GetProjects()
+	projectsIndex, err := h.projectService.GetAllProjects()
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "getting projects", err)
 	}
 
-	timelineViewTemplate := timeline.TimelineView(date, tasks, events, projectsIndex, nil)
+	timelineViewTemplate := timeline.TimelineView(date, tasks, events, weekEvents, projectsIndex, nil)
 
 	if err := htmx.NewResponse().RenderTempl(c.Request().Context(), c.Response().Writer, timelineViewTemplate); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "render template", err)
@@ -2,9 +2,58 @@ package utils
 
 import (
 	"fmt"
+	"sync/atomic"
 	"time"
 )
 
+// location is the time zone the user's days are in. Requests read it while
+// the settings may be changing it.
+var location atomic.Pointer[time.Location]
+
+// Location is the time zone days start and end in and times are shown in, the
+// machine's until one is set.
+func Location() *time.Location {
+	if loc := location.Load(); loc != nil {
+		return loc
+	}
+
+	return time.Local
+}
+
+// SetLocation changes the time zone of the user's days, nil goes back to the
+// machine's.
+func SetLocation(loc *time.Location) {
+	location.Store(loc)
+}
+
+// Now is the current time in the user's time zone, so its date is today.
+func Now() time.Time {
+	return time.Now().In(Location())
+}
+
+// StartOfDay is the midnight starting the day t falls on in the user's time
+// zone. Days are found by their date rather than by counting hours, so the
+// days clocks change on are 23 and 25 hours long.
+func StartOfDay(t time.Time) time.Time {
+	year, month, day := t.In(Location()).Date()
+
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, Location())
+
+	// Where the clocks go forward at midnight there is none, the day starts
+	// when they change rather than in the hour before it
+	if midnight.Day() != day {
+		_, midnight = midnight.ZoneBounds()
+	}
+
+	return midnight
+}
+
+// ParseDate reads a date as the day it is in the user's time zone rather than
+// in UTC.
+func ParseDate(layout string, value string) (time.Time, error) {
+	return time.ParseInLocation(layout, value, Location())
+}
+
 func FormatDuration(d int32) string {
 	duration := time.Duration(d) * time.Minute
 
@@ -20,5 +69,5 @@ func FormatDuration(d int32) string {
 }
 
 func FormatTime(t time.Time) string {
-	return t.Local().Format("03:04 PM")
+	return t.In(Location()).Format("03:04 PM")
 }
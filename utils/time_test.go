@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// useLocation makes loc the user's time zone for the rest of the test.
+func useLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("loading %s: %s", name, err)
+	}
+
+	SetLocation(loc)
+	t.Cleanup(func() { SetLocation(nil) })
+
+	return loc
+}
+
+func TestStartOfDay(t *testing.T) {
+	newYork := useLocation(t, "America/New_York")
+
+	tests := []struct {
+		name       string
+		t          time.Time
+		want       time.Time
+		wantLength time.Duration
+	}{
+		{
+			name:       "clocks going forward make a short day",
+			t:          time.Date(2025, 3, 9, 12, 0, 0, 0, newYork),
+			want:       time.Date(2025, 3, 9, 0, 0, 0, 0, newYork),
+			wantLength: 23 * time.Hour,
+		},
+		{
+			name:       "clocks going back make a long day",
+			t:          time.Date(2025, 11, 2, 23, 30, 0, 0, newYork),
+			want:       time.Date(2025, 11, 2, 0, 0, 0, 0, newYork),
+			wantLength: 25 * time.Hour,
+		},
+		{
+			name:       "the evening is the user's day although it's the next in UTC",
+			t:          time.Date(2025, 3, 10, 3, 30, 0, 0, time.UTC),
+			want:       time.Date(2025, 3, 9, 0, 0, 0, 0, newYork),
+			wantLength: 23 * time.Hour,
+		},
+		{
+			name:       "the morning in a zone ahead is still the day before",
+			t:          time.Date(2025, 11, 3, 2, 0, 0, 0, time.FixedZone("CET", 60*60)),
+			want:       time.Date(2025, 11, 2, 0, 0, 0, 0, newYork),
+			wantLength: 25 * time.Hour,
+		},
+		{
+			name:       "an ordinary day",
+			t:          time.Date(2025, 6, 2, 9, 0, 0, 0, newYork),
+			want:       time.Date(2025, 6, 2, 0, 0, 0, 0, newYork),
+			wantLength: 24 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := StartOfDay(tt.t)
+			if !got.Equal(tt.want) {
+				t.Fatalf("StartOfDay() = %s, want %s", got, tt.want)
+			}
+
+			if length := StartOfDay(got.AddDate(0, 0, 1)).Sub(got); length != tt.wantLength {
+				t.Errorf("the day lasts %s, want %s", length, tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestStartOfDayWithoutMidnight(t *testing.T) {
+	// Santiago's clocks go forward at midnight, the day starts at one
+	santiago := useLocation(t, "America/Santiago")
+
+	got := StartOfDay(time.Date(2025, 9, 7, 12, 0, 0, 0, santiago))
+
+	if got.Day() != 7 || got.Hour() != 1 {
+		t.Errorf("StartOfDay() = %s, want one in the morning of the 7th", got)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	newYork := useLocation(t, "America/New_York")
+
+	got, err := ParseDate("20060102", "20251102")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := time.Date(2025, 11, 2, 0, 0, 0, 0, newYork); !got.Equal(want) {
+		t.Errorf("ParseDate() = %s, want %s", got, want)
+	}
+
+	if got.Weekday() != time.Sunday {
+		t.Errorf("ParseDate() is a %s, want the Sunday it names", got.Weekday())
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	useLocation(t, "Europe/London")
+
+	// Half past two in New York is half past seven in London
+	got := FormatTime(time.Date(2025, 7, 4, 18, 30, 0, 0, time.UTC).In(time.FixedZone("EDT", -4*60*60)))
+
+	if got != "07:30 PM" {
+		t.Errorf("FormatTime() = %s, want 07:30 PM", got)
+	}
+}